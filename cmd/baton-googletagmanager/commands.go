@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"google.golang.org/api/tagmanager/v2"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
+)
+
+// loadConfig populates the config of a subcommand from the config file, the environment and its flags.
+// baton-sdk only loads the config of the connector command and does not export its loader, so this follows the same rules.
+func loadConfig(cmd *cobra.Command, cfg *config) (*viper.Viper, error) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+
+	cfgPath, cfgName, err := configPath(os.Getenv("BATON_CONFIG_PATH"))
+	if err != nil {
+		return nil, err
+	}
+
+	v.SetConfigName(cfgName)
+	v.AddConfigPath(cfgPath)
+
+	if err := v.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return nil, err
+		}
+	}
+
+	v.SetEnvPrefix("baton")
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.AutomaticEnv()
+	if err := v.BindPFlags(cmd.PersistentFlags()); err != nil {
+		return nil, err
+	}
+	if err := v.BindPFlags(cmd.Flags()); err != nil {
+		return nil, err
+	}
+
+	if err := v.Unmarshal(cfg); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
+
+// configPath returns the directory and the name without extension of the config file, defaulting to ./.baton.yaml.
+func configPath(customPath string) (string, string, error) {
+	if customPath == "" {
+		return ".", ".baton", nil
+	}
+
+	cfgDir, cfgFile := filepath.Split(filepath.Clean(customPath))
+	if cfgDir == "" {
+		cfgDir = "."
+	}
+
+	ext := filepath.Ext(cfgFile)
+	if ext != ".yaml" && ext != ".yml" {
+		return "", "", errors.New("expected config file to have .yaml or .yml extension")
+	}
+
+	return strings.TrimSuffix(cfgDir, string(filepath.Separator)), strings.TrimSuffix(cfgFile, ext), nil
+}
+
+// newFleetClient loads the config of the subcommand and returns a Tag Manager client authenticated with the configured credentials.
+func newFleetClient(ctx context.Context, cmd *cobra.Command, cfg *config) (*viper.Viper, *tagmanager.Service, error) {
+	v, err := loadConfig(cmd, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if cfg.CredentialsJSONFilePath == "" {
+		return nil, nil, fmt.Errorf("path to credentials JSON file is required, use --help for more information")
+	}
+
+	ac, _, err := authCredentials(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client, err := gtm.NewService(ctx, ac)
+	if err != nil {
+		return nil, nil, fmt.Errorf("googletagmanager-fleet: %w", err)
+	}
+
+	return v, client, nil
+}
+
+// openOutput returns the file at path to write a report to, or stdout when path is empty.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating output file: %w", err)
+	}
+
+	return f, nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// writeReport writes the report in the format selected by the flags of the subcommand.
+func writeReport(v *viper.Viper, r fleet.Report) error {
+	format := v.GetString("format")
+	if err := fleet.ValidateFormat(format); err != nil {
+		return err
+	}
+
+	out, err := openOutput(v.GetString("output"))
+	if err != nil {
+		return err
+	}
+
+	err = fleet.WriteReport(out, format, r)
+	if err != nil {
+		_ = out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// reportFlags sets the flags selecting where and how a report is written.
+func reportFlags(cmd *cobra.Command) {
+	cmd.Flags().String("format", fleet.FormatJSON, "Output format of the report: json or csv")
+	cmd.Flags().String("output", "", "Path of the file to write the report to, defaults to stdout")
+}
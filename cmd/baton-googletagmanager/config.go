@@ -6,6 +6,8 @@ import (
 
 	"github.com/conductorone/baton-sdk/pkg/cli"
 	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/connector"
 )
 
 // config defines the external configuration required for the connector to run.
@@ -14,6 +16,19 @@ type config struct {
 
 	CredentialsJSONFilePath string   `mapstructure:"credentials-json-file-path"`
 	Accounts                []string `mapstructure:"accounts"`
+	RoleResources           bool     `mapstructure:"role-resources"`
+	AccountRevokePolicy     string   `mapstructure:"account-revoke-policy"`
+	AllowLastAdminRemoval   bool     `mapstructure:"allow-last-admin-removal"`
+	AllowedDomains          []string `mapstructure:"allowed-domains"`
+	AccountAllowedDomains   []string `mapstructure:"account-allowed-domains"`
+	InternalDomains         []string `mapstructure:"internal-domains"`
+	DryRun                  bool     `mapstructure:"dry-run"`
+	AuditLogPath            string   `mapstructure:"audit-log-path"`
+	AllowContainerDeletion  bool     `mapstructure:"allow-container-deletion"`
+	SyncWorkspaces          bool     `mapstructure:"sync-workspaces"`
+	SyncDestinations        bool     `mapstructure:"sync-destinations"`
+	SyncCustomTemplates     bool     `mapstructure:"sync-custom-templates"`
+	SyncContainerAssets     bool     `mapstructure:"sync-container-assets"`
 }
 
 // validateConfig is run after the configuration is loaded, and should return an error if it isn't valid.
@@ -22,6 +37,14 @@ func validateConfig(ctx context.Context, cfg *config) error {
 		return fmt.Errorf("path to credentials JSON file is required, use --help for more information")
 	}
 
+	if _, err := connector.ParseRevokePolicy(cfg.AccountRevokePolicy); err != nil {
+		return err
+	}
+
+	if _, err := connector.NewProvisioningPolicy(cfg.AllowedDomains, cfg.AccountAllowedDomains); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -33,4 +56,53 @@ func cmdFlags(cmd *cobra.Command) {
 		"Path to the credentials JSON file for the service account to use for authentication with Google Tag Manager ($BATON_CREDENTIALS_JSON_FILE_PATH)",
 	)
 	cmd.PersistentFlags().StringSlice("accounts", []string{}, "Limit syncing to the specified accounts ($BATON_ACCOUNTS)")
+	cmd.PersistentFlags().String(
+		"account-revoke-policy",
+		string(connector.RevokePolicyDeleteCascade),
+		"What happens when the minimal account access of a user is revoked: delete-cascade, downgrade-only or refuse-if-container-access ($BATON_ACCOUNT_REVOKE_POLICY)",
+	)
+	cmd.PersistentFlags().Bool(
+		"allow-last-admin-removal",
+		false,
+		"Allow revoking the admin permission of the last human admin of an account ($BATON_ALLOW_LAST_ADMIN_REMOVAL)",
+	)
+	cmd.PersistentFlags().StringSlice(
+		"allowed-domains",
+		[]string{},
+		"Only grant access to identities with an email in one of these domains ($BATON_ALLOWED_DOMAINS)",
+	)
+	cmd.PersistentFlags().StringSlice(
+		"account-allowed-domains",
+		[]string{},
+		"Per account overrides of the allowed domains as accountID:domain pairs ($BATON_ACCOUNT_ALLOWED_DOMAINS)",
+	)
+	cmd.PersistentFlags().StringSlice(
+		"internal-domains",
+		[]string{},
+		"Email domains of internal identities, users outside of them are flagged as external collaborators ($BATON_INTERNAL_DOMAINS)",
+	)
+	cmd.PersistentFlags().Bool("dry-run", false, "Log the user permission changes of grants and revokes and the containers and workspaces created or deleted without applying them ($BATON_DRY_RUN)")
+	cmd.PersistentFlags().String(
+		"audit-log-path",
+		"",
+		"Path of a file to append a JSON line to for every user permission change and container or workspace creation or deletion made by the connector ($BATON_AUDIT_LOG_PATH)",
+	)
+	cmd.PersistentFlags().Bool("allow-container-deletion", false, "Allow deleting containers through the connector ($BATON_ALLOW_CONTAINER_DELETION)")
+	cmd.PersistentFlags().Bool("sync-workspaces", false, "Sync the workspaces of every container, one extra API call per container ($BATON_SYNC_WORKSPACES)")
+	cmd.PersistentFlags().Bool(
+		"sync-destinations",
+		false,
+		"Sync the Google tag destinations linked to every container, one extra API call per container ($BATON_SYNC_DESTINATIONS)",
+	)
+	cmd.PersistentFlags().Bool(
+		"sync-custom-templates",
+		false,
+		"Sync the custom templates in the live version of every container, one extra API call per container ($BATON_SYNC_CUSTOM_TEMPLATES)",
+	)
+	cmd.PersistentFlags().Bool(
+		"sync-container-assets",
+		false,
+		"Reference the live version and install snippet of every container as assets, one extra API call per container ($BATON_SYNC_CONTAINER_ASSETS)",
+	)
+	cmd.PersistentFlags().Bool("role-resources", false, "Sync account and container roles as role resources with member grants ($BATON_ROLE_RESOURCES)")
 }
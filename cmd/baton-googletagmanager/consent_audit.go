@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+// consentAuditCmd reports the tags of the live versions without the required consent checks.
+// It fails when there are more violations than allowed so it can gate pipelines.
+func consentAuditCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consent-audit",
+		Short: "Audit the consent settings of the tags of the live container versions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			versions, err := fleet.LiveVersions(ctx, client, cfg.Accounts)
+			if err != nil {
+				return err
+			}
+
+			report := fleet.NewConsentReport(versions, v.GetStringSlice("required-consent-types"), v.GetStringSlice("consent-tag-types"))
+			err = writeReport(v, report)
+			if err != nil {
+				return err
+			}
+
+			maxViolations := v.GetInt("max-violations")
+			if report.ExceedsViolations(maxViolations) {
+				return fmt.Errorf("consent audit found %d violations, more than the %d allowed", report.Violations, maxViolations)
+			}
+
+			return nil
+		},
+	}
+
+	reportFlags(cmd)
+	cmd.Flags().StringSlice(
+		"required-consent-types",
+		[]string{},
+		"Consent types every tag requiring consent must check, e.g. ad_storage ($BATON_REQUIRED_CONSENT_TYPES)",
+	)
+	cmd.Flags().StringSlice(
+		"consent-tag-types",
+		[]string{},
+		"Only audit tags of these types, defaults to all tags ($BATON_CONSENT_TAG_TYPES)",
+	)
+	cmd.Flags().Int("max-violations", 0, "Number of violations allowed before the audit fails ($BATON_MAX_VIOLATIONS)")
+
+	return cmd
+}
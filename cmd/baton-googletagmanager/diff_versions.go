@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+const formatText = "text"
+
+// diffVersionsCmd prints the changes between two versions of a container, by default between the live version and the one before it.
+func diffVersionsCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff-versions",
+		Short: "Show the changes of the tags, triggers, variables, templates, clients and zones between two versions of a container",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			format := v.GetString("format")
+			if format != formatText && format != fleet.FormatJSON {
+				return fmt.Errorf("unsupported output format %q, expected %s or %s", format, formatText, fleet.FormatJSON)
+			}
+
+			containerID := v.GetString("container")
+			if containerID == "" {
+				return fmt.Errorf("--container is required")
+			}
+
+			container, err := fleet.FindContainer(ctx, client, cfg.Accounts, containerID)
+			if err != nil {
+				return err
+			}
+
+			toID := v.GetString("to-version")
+			if toID == "" {
+				toID, err = fleet.LiveVersionID(ctx, client, container)
+				if err != nil {
+					return err
+				}
+			}
+
+			fromID := v.GetString("from-version")
+			if fromID == "" {
+				fromID, err = fleet.PreviousVersionID(ctx, client, container, toID)
+				if err != nil {
+					return err
+				}
+			}
+
+			from, err := fleet.GetVersion(ctx, client, container, fromID)
+			if err != nil {
+				return err
+			}
+
+			to, err := fleet.GetVersion(ctx, client, container, toID)
+			if err != nil {
+				return err
+			}
+
+			diff, err := fleet.DiffVersions(from, to)
+			if err != nil {
+				return err
+			}
+
+			out, err := openOutput(v.GetString("output"))
+			if err != nil {
+				return err
+			}
+
+			if format == formatText {
+				err = diff.WriteText(out)
+			} else {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(diff)
+			}
+			if err != nil {
+				_ = out.Close()
+				return err
+			}
+
+			return out.Close()
+		},
+	}
+
+	cmd.Flags().String("container", "", "Id or public id of the container to compare the versions of")
+	cmd.Flags().String("from-version", "", "Id of the version to compare from, defaults to the version before the one compared to")
+	cmd.Flags().String("to-version", "", "Id of the version to compare to, defaults to the live version")
+	cmd.Flags().String("format", formatText, "Output format of the diff: text or json")
+	cmd.Flags().String("output", "", "Path of the file to write the diff to, defaults to stdout")
+
+	return cmd
+}
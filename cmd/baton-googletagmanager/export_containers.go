@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+// exportContainersCmd backs up the containers of the configured accounts as files the Tag Manager UI can import.
+func exportContainersCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-containers",
+		Short: "Export the live version of every container, and optionally its workspaces, in the Tag Manager import format",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			dir := v.GetString("export-dir")
+			if dir == "" {
+				return fmt.Errorf("--export-dir is required")
+			}
+
+			manifest, err := fleet.Export(ctx, client, cfg.Accounts, dir, v.GetBool("include-workspaces"))
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "exported %d files to %s\n", len(manifest.Entries), dir)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("export-dir", "", "Directory to write the account/container/version.json files and the manifest to ($BATON_EXPORT_DIR)")
+	cmd.Flags().Bool("include-workspaces", false, "Also export the current state of every workspace ($BATON_INCLUDE_WORKSPACES)")
+
+	return cmd
+}
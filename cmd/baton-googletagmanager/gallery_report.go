@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+// galleryReportCmd reports the Community Template Gallery templates of the live versions that are modified or pinned to a different signature than the rest of the fleet.
+func galleryReportCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gallery-report",
+		Short: "Report stale Community Template Gallery templates across the live container versions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			versions, err := fleet.LiveVersions(ctx, client, cfg.Accounts)
+			if err != nil {
+				return err
+			}
+
+			return writeReport(v, fleet.NewGalleryReport(versions))
+		},
+	}
+
+	reportFlags(cmd)
+
+	return cmd
+}
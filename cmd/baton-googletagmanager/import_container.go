@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+// importContainerCmd creates the entities of a container export in a workspace of another container.
+func importContainerCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import-container",
+		Short: "Import a Tag Manager container export into a workspace",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			mode, err := fleet.ParseImportMode(v.GetString("import-mode"))
+			if err != nil {
+				return err
+			}
+
+			importFile := v.GetString("import-file")
+			if importFile == "" {
+				return fmt.Errorf("--import-file is required")
+			}
+
+			containerID := v.GetString("container")
+			if containerID == "" {
+				return fmt.Errorf("--container is required")
+			}
+
+			ef, err := fleet.ReadExportFile(importFile)
+			if err != nil {
+				return err
+			}
+
+			container, err := fleet.FindContainer(ctx, client, cfg.Accounts, containerID)
+			if err != nil {
+				return err
+			}
+
+			workspace, err := fleet.FindWorkspace(ctx, client, container, v.GetString("workspace"))
+			if err != nil {
+				return err
+			}
+
+			dryRun := v.GetBool("plan")
+			actions, err := fleet.Import(ctx, client, ef.ContainerVersion, container, workspace, mode, dryRun)
+			for _, a := range actions {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s %s %q %s -> %s\n", a.Action, a.Kind, a.Name, a.SourceID, a.TargetID)
+			}
+			if err != nil {
+				return err
+			}
+
+			if dryRun {
+				fmt.Fprintf(cmd.OutOrStdout(), "plan only, nothing was imported into workspace %q of container %s\n", workspace.Name, container.PublicId)
+				return nil
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "imported %s into workspace %q of container %s\n", importFile, workspace.Name, container.PublicId)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("import-file", "", "Path of the container export JSON to import ($BATON_IMPORT_FILE)")
+	cmd.Flags().String("container", "", "Id or public id of the container to import into ($BATON_CONTAINER)")
+	cmd.Flags().String("workspace", "", "Id of the workspace to import into, defaults to the default workspace ($BATON_WORKSPACE)")
+	cmd.Flags().Bool("plan", false, "Report the actions the import would take without changing the workspace ($BATON_PLAN)")
+	cmd.Flags().String(
+		"import-mode",
+		string(fleet.ImportModeMerge),
+		"What happens to entities existing under the same name and type: merge keeps them, overwrite replaces them ($BATON_IMPORT_MODE)",
+	)
+
+	return cmd
+}
@@ -31,6 +31,13 @@ func main() {
 
 	cmd.Version = version
 	cmdFlags(cmd)
+	cmd.AddCommand(galleryReportCmd(ctx, cfg))
+	cmd.AddCommand(scanCmd(ctx, cfg))
+	cmd.AddCommand(consentAuditCmd(ctx, cfg))
+	cmd.AddCommand(diffVersionsCmd(ctx, cfg))
+	cmd.AddCommand(exportContainersCmd(ctx, cfg))
+	cmd.AddCommand(importContainerCmd(ctx, cfg))
+	cmd.AddCommand(promoteCmd(ctx, cfg))
 
 	err = cmd.Execute()
 	if err != nil {
@@ -42,28 +49,45 @@ func main() {
 func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, error) {
 	l := ctxzap.Extract(ctx)
 
-	var ac uhttp.AuthCredentials = &uhttp.NoAuth{}
-	if cfg.CredentialsJSONFilePath != "" {
-		credentials, err := os.ReadFile(cfg.CredentialsJSONFilePath)
+	ac, credentials, err := authCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	revokePolicy, err := connector.ParseRevokePolicy(cfg.AccountRevokePolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := connector.NewProvisioningPolicy(cfg.AllowedDomains, cfg.AccountAllowedDomains)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []connector.Option{
+		connector.WithRoleResources(cfg.RoleResources),
+		connector.WithRevokePolicy(revokePolicy),
+		connector.WithAllowLastAdminRemoval(cfg.AllowLastAdminRemoval),
+		connector.WithProvisioningPolicy(policy),
+		connector.WithInternalDomains(cfg.InternalDomains),
+		connector.WithDryRun(cfg.DryRun),
+		connector.WithContainerDeletion(cfg.AllowContainerDeletion),
+		connector.WithWorkspaces(cfg.SyncWorkspaces),
+		connector.WithDestinations(cfg.SyncDestinations),
+		connector.WithCustomTemplates(cfg.SyncCustomTemplates),
+		connector.WithContainerAssets(cfg.SyncContainerAssets),
+	}
+
+	if cfg.AuditLogPath != "" {
+		auditLog, err := connector.NewAuditLog(cfg.AuditLogPath, actorFromCredentials(credentials))
 		if err != nil {
-			return nil, fmt.Errorf("error reading credentials JSON file: %w", err)
+			return nil, err
 		}
 
-		ac = uhttp.NewOAuth2JWT(
-			credentials,
-			[]string{
-				tagmanager.TagmanagerManageAccountsScope,
-				tagmanager.TagmanagerManageUsersScope,
-				tagmanager.TagmanagerEditContainersScope,
-				tagmanager.TagmanagerEditContainerversionsScope,
-				tagmanager.TagmanagerDeleteContainersScope,
-				tagmanager.TagmanagerPublishScope,
-			},
-			google.JWTConfigFromJSON,
-		)
+		opts = append(opts, connector.WithAuditLog(auditLog))
 	}
 
-	cb, err := connector.New(ctx, ac, cfg.Accounts)
+	cb, err := connector.New(ctx, ac, cfg.Accounts, opts...)
 	if err != nil {
 		l.Error("error creating connector", zap.Error(err))
 		return nil, err
@@ -77,3 +101,44 @@ func getConnector(ctx context.Context, cfg *config) (types.ConnectorServer, erro
 
 	return c, nil
 }
+
+// authCredentials returns the credentials to authenticate with Google Tag Manager and the raw service account JSON they were read from.
+func authCredentials(cfg *config) (uhttp.AuthCredentials, []byte, error) {
+	if cfg.CredentialsJSONFilePath == "" {
+		return &uhttp.NoAuth{}, nil, nil
+	}
+
+	credentials, err := os.ReadFile(cfg.CredentialsJSONFilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading credentials JSON file: %w", err)
+	}
+
+	ac := uhttp.NewOAuth2JWT(
+		credentials,
+		[]string{
+			tagmanager.TagmanagerManageAccountsScope,
+			tagmanager.TagmanagerManageUsersScope,
+			tagmanager.TagmanagerEditContainersScope,
+			tagmanager.TagmanagerEditContainerversionsScope,
+			tagmanager.TagmanagerDeleteContainersScope,
+			tagmanager.TagmanagerPublishScope,
+		},
+		google.JWTConfigFromJSON,
+	)
+
+	return ac, credentials, nil
+}
+
+// actorFromCredentials returns the service account email the connector authenticates as, used to attribute audited changes.
+func actorFromCredentials(credentials []byte) string {
+	if len(credentials) == 0 {
+		return ""
+	}
+
+	jwtConfig, err := google.JWTConfigFromJSON(credentials)
+	if err != nil {
+		return ""
+	}
+
+	return jwtConfig.Email
+}
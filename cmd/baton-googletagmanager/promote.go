@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+// promoteCmd applies the live version of a source container to the default workspace of a target container with the same structure.
+func promoteCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Create or update the entities of a target container's default workspace that differ from a source container's live version",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			sourceID := v.GetString("source-container")
+			targetID := v.GetString("target-container")
+			if sourceID == "" || targetID == "" {
+				return fmt.Errorf("--source-container and --target-container are required")
+			}
+
+			source, err := fleet.FindContainer(ctx, client, cfg.Accounts, sourceID)
+			if err != nil {
+				return err
+			}
+
+			target, err := fleet.FindContainer(ctx, client, cfg.Accounts, targetID)
+			if err != nil {
+				return err
+			}
+
+			if source.ContainerId == target.ContainerId {
+				return fmt.Errorf("the source and target containers must differ")
+			}
+
+			result, err := fleet.Promote(ctx, client, source, target, v.GetBool("create-version"), v.GetString("version-name"))
+			if result != nil {
+				for _, a := range result.Actions {
+					if a.Action == fleet.ImportActionUnchanged {
+						continue
+					}
+
+					fmt.Fprintf(cmd.OutOrStdout(), "%s %s %q\n", a.Action, a.Kind, a.Name)
+				}
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case !result.Changed():
+				fmt.Fprintf(cmd.OutOrStdout(), "workspace %q of %s already matches %s version %s\n", result.Workspace, target.PublicId, source.PublicId, result.SourceVersionID)
+			case result.Version != nil:
+				fmt.Fprintf(cmd.OutOrStdout(), "created version %s of %s, review and publish it at %s\n", result.Version.ContainerVersionId, target.PublicId, result.Version.TagManagerUrl)
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "updated workspace %q of %s, create a version and publish it once reviewed\n", result.Workspace, target.PublicId)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("source-container", "", "Id or public id of the container whose live version is promoted ($BATON_SOURCE_CONTAINER)")
+	cmd.Flags().String("target-container", "", "Id or public id of the container whose default workspace is updated ($BATON_TARGET_CONTAINER)")
+	cmd.Flags().Bool("create-version", false, "Create a version from the updated workspace, it is not published ($BATON_CREATE_VERSION)")
+	cmd.Flags().String("version-name", "", "Name of the created version, defaults to one naming the source container and version ($BATON_VERSION_NAME)")
+
+	return cmd
+}
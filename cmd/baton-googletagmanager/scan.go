@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/fleet"
+)
+
+// scanCmd reports the urls loaded by custom HTML and custom image tags of the live versions from domains outside of the allowlist.
+func scanCmd(ctx context.Context, cfg *config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Scan the custom HTML and custom image tags of the live container versions for third-party urls",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v, client, err := newFleetClient(ctx, cmd, cfg)
+			if err != nil {
+				return err
+			}
+
+			versions, err := fleet.LiveVersions(ctx, client, cfg.Accounts)
+			if err != nil {
+				return err
+			}
+
+			return writeReport(v, fleet.NewScanReport(versions, v.GetStringSlice("script-allowed-domains")))
+		},
+	}
+
+	reportFlags(cmd)
+	cmd.Flags().StringSlice(
+		"script-allowed-domains",
+		[]string{},
+		"Domains custom tags may load scripts, images and iframes from, including their subdomains ($BATON_SCRIPT_ALLOWED_DOMAINS)",
+	)
+
+	return cmd
+}
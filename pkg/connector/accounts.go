@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"slices"
-	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -15,6 +14,10 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 	"google.golang.org/api/tagmanager/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 const (
@@ -29,42 +32,94 @@ const (
 	ReadRole                           = "read"
 )
 
+// RevokePolicy controls what happens to the remaining access of a user when their minimal account access is revoked.
+type RevokePolicy string
+
+const (
+	// RevokePolicyDeleteCascade deletes the user permission together with all container access.
+	RevokePolicyDeleteCascade RevokePolicy = "delete-cascade"
+	// RevokePolicyDowngradeOnly only allows downgrading admins and refuses to remove account access.
+	RevokePolicyDowngradeOnly RevokePolicy = "downgrade-only"
+	// RevokePolicyRefuseIfContainerAccess deletes the user permission only when no container access is left.
+	RevokePolicyRefuseIfContainerAccess RevokePolicy = "refuse-if-container-access"
+)
+
+var revokePolicies = []RevokePolicy{
+	RevokePolicyDeleteCascade,
+	RevokePolicyDowngradeOnly,
+	RevokePolicyRefuseIfContainerAccess,
+}
+
+// ParseRevokePolicy returns the revoke policy with the given name, defaulting to RevokePolicyDeleteCascade when empty.
+func ParseRevokePolicy(name string) (RevokePolicy, error) {
+	if name == "" {
+		return RevokePolicyDeleteCascade, nil
+	}
+
+	if !slices.Contains(revokePolicies, RevokePolicy(name)) {
+		return "", fmt.Errorf("googletagmanager-connector: invalid revoke policy %q, expected one of %v", name, revokePolicies)
+	}
+
+	return RevokePolicy(name), nil
+}
+
 var (
 	accountPermissions = []string{
-		RolePermissionUnspecifiedRole,
 		AdminRole,
-		NoAccessRole,
 		UserRole,
 	}
 	containerPermissions = []string{
 		ApproveRole,
-		ContainerPermissionUnspecifiedRole,
 		EditRole,
 		PublishRole,
 		ReadRole,
+	}
+
+	// noAccessPermissions are permissions the API uses to express the absence of access.
+	// They are not exposed as entitlements, but are still written when revoking access.
+	noAccessPermissions = []string{
+		RolePermissionUnspecifiedRole,
+		ContainerPermissionUnspecifiedRole,
 		NoAccessRole,
 	}
 )
 
 type accountBuilder struct {
-	client       *tagmanager.Service
-	resourceType *v2.ResourceType
-	accountMap   map[string]struct{}
+	client        *tagmanager.Service
+	resourceType  *v2.ResourceType
+	accountMap    map[string]struct{}
+	roleResources bool
+	revokePolicy  RevokePolicy
+	// disables the guardrail preventing revokes that leave an account without a human admin
+	allowLastAdminRemoval bool
+	policy                *ProvisioningPolicy
+	internalDomains       []string
+	writer                *mutationWriter
 }
 
 func (a *accountBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return accountResourceType
 }
 
-func accountResource(ctx context.Context, account *tagmanager.Account) (*v2.Resource, error) {
-	resource, err := rs.NewResource(
+func accountResource(ctx context.Context, account *tagmanager.Account, roleResources bool, profile map[string]interface{}) (*v2.Resource, error) {
+	childTypes := []proto.Message{
+		&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+		&v2.ChildResourceType{ResourceTypeId: containerResourceType.Id},
+	}
+
+	if roleResources {
+		childTypes = append(childTypes,
+			&v2.ChildResourceType{ResourceTypeId: accountRoleResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: containerRoleResourceType.Id},
+		)
+	}
+
+	resource, err := rs.NewAppResource(
 		account.Name,
 		accountResourceType,
 		account.AccountId,
-		rs.WithAnnotation(
-			&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
-			&v2.ChildResourceType{ResourceTypeId: containerResourceType.Id},
-		),
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithAnnotation(childTypes...),
 	)
 
 	if err != nil {
@@ -98,7 +153,20 @@ func (a *accountBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pag
 			continue
 		}
 
-		ar, err := accountResource(ctx, acc)
+		profile := map[string]interface{}{
+			"account_id": acc.AccountId,
+		}
+
+		if len(a.internalDomains) > 0 {
+			external, err := a.countExternalCollaborators(ctx, acc.AccountId)
+			if err != nil {
+				return nil, "", nil, err
+			}
+
+			profile["external_collaborators"] = external
+		}
+
+		ar, err := accountResource(ctx, acc, a.roleResources, profile)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -114,6 +182,39 @@ func (a *accountBuilder) List(ctx context.Context, _ *v2.ResourceId, pToken *pag
 	return rv, nextPage, nil, nil
 }
 
+// countExternalCollaborators returns the number of users on the account with an email outside the internal domains.
+func (a *accountBuilder) countExternalCollaborators(ctx context.Context, accID string) (int, error) {
+	external := 0
+	pageToken := ""
+	for {
+		parentPath := fmt.Sprintf("accounts/%s", accID)
+		upl := a.client.Accounts.UserPermissions.List(parentPath).Context(ctx)
+
+		if pageToken != "" {
+			upl = upl.PageToken(pageToken)
+		}
+
+		ups, err := upl.Do()
+		if err != nil {
+			return 0, fmt.Errorf("googletagmanager-connector: failed to list user permissions: %w", err)
+		}
+
+		for _, up := range ups.UserPermission {
+			if isExternalPrincipal(a.internalDomains, up.EmailAddress) {
+				external++
+			}
+		}
+
+		if ups.NextPageToken == "" {
+			break
+		}
+
+		pageToken = ups.NextPageToken
+	}
+
+	return external, nil
+}
+
 // Entitlements returns slice of entititlements representing all possible permissions user can have on the account.
 func (a *accountBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
@@ -165,13 +266,17 @@ func (a *accountBuilder) Grants(ctx context.Context, resource *v2.Resource, pTok
 			return nil, "", nil, fmt.Errorf("googletagmanager-connector: found invalid account id: %s", up.AccountId)
 		}
 
+		if up.AccountAccess == nil || isNoAccessPermission(up.AccountAccess.Permission) {
+			continue
+		}
+
 		if !slices.Contains(accountPermissions, up.AccountAccess.Permission) {
 			l.Warn("found invalid permission during account grant creation", zap.String("permission", up.AccountAccess.Permission))
 
 			continue
 		}
 
-		id := fmt.Sprintf("%s:%s", accID, up.EmailAddress)
+		id := newUserID(accID, up.EmailAddress)
 		principalID, err := rs.NewResourceID(userResourceType, id)
 		if err != nil {
 			return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to create resource id: %w", err)
@@ -190,6 +295,7 @@ func (a *accountBuilder) Grants(ctx context.Context, resource *v2.Resource, pTok
 
 func (a *accountBuilder) FindRelevantPermissions(ctx context.Context, accID, userID, permission string, revoke bool) ([]string, error) {
 	var rv []string
+	found := false
 	pageToken := ""
 
 	for {
@@ -205,14 +311,13 @@ func (a *accountBuilder) FindRelevantPermissions(ctx context.Context, accID, use
 			return nil, fmt.Errorf("googletagmanager-connector: failed to list user permissions: %w", err)
 		}
 
-		uParts := strings.Split(userID, ":")
-		if len(uParts) != 2 {
-			return nil, fmt.Errorf("googletagmanager-connector: invalid user id: %s", userID)
+		_, mail, err := parseUserID(userID)
+		if err != nil {
+			return nil, err
 		}
 
-		mail := uParts[1]
 		for _, up := range ups.UserPermission {
-			if up.EmailAddress != mail {
+			if !emailsMatch(up.EmailAddress, mail) {
 				continue
 			}
 
@@ -220,15 +325,10 @@ func (a *accountBuilder) FindRelevantPermissions(ctx context.Context, accID, use
 				continue
 			}
 
-			if revoke && up.AccountAccess.Permission != permission {
-				continue
+			found = true
+			if accountAccessNeedsChange(up, permission, revoke) {
+				rv = append(rv, up.Path)
 			}
-
-			if !revoke && up.AccountAccess.Permission == permission {
-				continue
-			}
-
-			rv = append(rv, up.Path)
 		}
 
 		if ups.NextPageToken == "" {
@@ -238,9 +338,23 @@ func (a *accountBuilder) FindRelevantPermissions(ctx context.Context, accID, use
 		pageToken = ups.NextPageToken
 	}
 
+	if !revoke && !found {
+		return nil, status.Errorf(codes.NotFound, "googletagmanager-connector: user %s has no access to account %s", userID, accID)
+	}
+
 	return rv, nil
 }
 
+// accountAccessNeedsChange reports whether granting or revoking permission on
+// the account changes the user permission.
+func accountAccessNeedsChange(up *tagmanager.UserPermission, permission string, revoke bool) bool {
+	if up.AccountAccess == nil {
+		return !revoke
+	}
+
+	return (up.AccountAccess.Permission == permission) == revoke
+}
+
 func (a *accountBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) (annotations.Annotations, error) {
 	l := ctxzap.Extract(ctx)
 
@@ -255,6 +369,16 @@ func (a *accountBuilder) Grant(ctx context.Context, principal *v2.Resource, enti
 	}
 
 	accID, userID, permission := entitlement.Resource, principal.Id.Resource, entitlement.Slug
+	_, mail, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.policy.Check(accID.Id.Resource, mail)
+	if err != nil {
+		return nil, err
+	}
+
 	pPaths, err := a.FindRelevantPermissions(ctx, accID.Id.Resource, userID, permission, false)
 	if err != nil {
 		return nil, err
@@ -278,15 +402,25 @@ func (a *accountBuilder) Grant(ctx context.Context, principal *v2.Resource, enti
 		}
 
 		// update existing permission
+		before := cloneUserPermission(pg)
 		pg.AccountAccess = &tagmanager.AccountAccess{
 			Permission: permission,
 		}
 
 		// update in API
-		_, err = a.client.Accounts.UserPermissions.Update(pPath, pg).Context(ctx).Do()
+		applied, err := a.writer.update(ctx, pPath, before, pg)
 		if err != nil {
 			return nil, fmt.Errorf("googletagmanager-connector: failed to grant permission: %w", err)
 		}
+
+		if !applied {
+			continue
+		}
+
+		_, err = verifyAccountAccess(ctx, a.client, pPath, permission)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return nil, nil
@@ -325,6 +459,7 @@ func (a *accountBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotatio
 		return nil, nil
 	}
 
+	var cascaded []*v2.Grant
 	for _, pPath := range pPaths {
 		// when revoking a admin permission, set permission to minimal user permission
 		if permission == AdminRole {
@@ -333,37 +468,207 @@ func (a *accountBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotatio
 				return nil, fmt.Errorf("googletagmanager-connector: failed to get permission: %w", err)
 			}
 
+			err = a.ensureNotLastAdmin(ctx, pg)
+			if err != nil {
+				return nil, err
+			}
+
+			before := cloneUserPermission(pg)
 			pg.AccountAccess = &tagmanager.AccountAccess{
 				Permission: UserRole,
 			}
 
-			_, err = a.client.Accounts.UserPermissions.Update(pPath, pg).Context(ctx).Do()
+			applied, err := a.writer.update(ctx, pPath, before, pg)
 			if err != nil {
 				return nil, fmt.Errorf("googletagmanager-connector: failed to revoke permission: %w", err)
 			}
 
+			if applied {
+				_, err = verifyAccountAccess(ctx, a.client, pPath, UserRole)
+				if err != nil {
+					return nil, err
+				}
+			}
+
 			continue
 		}
 
 		// when revoking a minimal user permission or any other, revoke the whole access to the account
-		err := a.client.Accounts.UserPermissions.Delete(pPath).Context(ctx).Do()
+		removed, err := a.deleteUserPermission(ctx, pPath)
 		if err != nil {
-			return nil, fmt.Errorf("googletagmanager-connector: failed to revoke permission: %w", err)
+			return nil, err
 		}
+
+		cascaded = append(cascaded, removed...)
 	}
 
-	return nil, nil
+	if len(cascaded) == 0 {
+		return nil, nil
+	}
+
+	meta, err := cascadedGrantsMetadata(cascaded, a.writer.dryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return annotations.New(meta), nil
+}
+
+// cascadedGrantsMetadata describes the container grants removed along with the account access.
+// In dry-run mode they are the grants that would have been removed.
+func cascadedGrantsMetadata(grants []*v2.Grant, dryRun bool) (*v2.GrantMetadata, error) {
+	ids := make([]interface{}, 0, len(grants))
+	for _, g := range grants {
+		ids = append(ids, g.Id)
+	}
+
+	md, err := structpb.NewStruct(map[string]interface{}{
+		"cascade_revoked_grant_ids": ids,
+		"dry_run":                   dryRun,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to create cascaded grants metadata: %w", err)
+	}
+
+	return &v2.GrantMetadata{Metadata: md}, nil
+}
+
+// deleteUserPermission removes the whole access to the account as allowed by the revoke policy.
+// It returns the container grants removed along with the account access, or that would have been removed in dry-run mode.
+func (a *accountBuilder) deleteUserPermission(ctx context.Context, pPath string) ([]*v2.Grant, error) {
+	if a.revokePolicy == RevokePolicyDowngradeOnly {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"googletagmanager-connector: revoke policy %s does not allow removing account access",
+			a.revokePolicy,
+		)
+	}
+
+	pg, err := a.client.Accounts.UserPermissions.Get(pPath).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to get permission: %w", err)
+	}
+
+	err = a.ensureNotLastAdmin(ctx, pg)
+	if err != nil {
+		return nil, err
+	}
+
+	grants, err := grantsForUserPermission(pg)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []*v2.Grant
+	for _, g := range grants {
+		if g.Entitlement.Resource.Id.ResourceType == containerResourceType.Id {
+			removed = append(removed, g)
+		}
+	}
+
+	if len(removed) > 0 && a.revokePolicy == RevokePolicyRefuseIfContainerAccess {
+		return nil, status.Errorf(
+			codes.FailedPrecondition,
+			"googletagmanager-connector: revoke policy %s does not allow removing account access of %s with container access, observed %s",
+			a.revokePolicy,
+			pg.EmailAddress,
+			describeUserPermission(pg),
+		)
+	}
+
+	applied, err := a.writer.delete(ctx, pPath, pg)
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to revoke permission: %w", err)
+	}
+
+	if !applied {
+		return removed, nil
+	}
+
+	err = verifyUserPermissionDeleted(ctx, a.client, pPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
+
+// ensureNotLastAdmin refuses removing the admin permission of the user when no other human admin would remain on the account.
+func (a *accountBuilder) ensureNotLastAdmin(ctx context.Context, target *tagmanager.UserPermission) error {
+	if a.allowLastAdminRemoval || target.AccountAccess == nil || target.AccountAccess.Permission != AdminRole {
+		return nil
+	}
+
+	admins := 0
+	pageToken := ""
+	for {
+		parentPath := fmt.Sprintf("accounts/%s", target.AccountId)
+		upl := a.client.Accounts.UserPermissions.List(parentPath).Context(ctx)
+
+		if pageToken != "" {
+			upl = upl.PageToken(pageToken)
+		}
+
+		ups, err := upl.Do()
+		if err != nil {
+			return fmt.Errorf("googletagmanager-connector: failed to list user permissions: %w", err)
+		}
+
+		for _, up := range ups.UserPermission {
+			if up.Path == target.Path || up.AccountAccess == nil || up.AccountAccess.Permission != AdminRole {
+				continue
+			}
+
+			if !isHumanPrincipal(up.EmailAddress) {
+				continue
+			}
+
+			admins++
+		}
+
+		if ups.NextPageToken == "" {
+			break
+		}
+
+		pageToken = ups.NextPageToken
+	}
+
+	if admins == 0 {
+		return status.Errorf(
+			codes.FailedPrecondition,
+			"googletagmanager-connector: refusing to remove admin permission of %s, account %s would be left without a human admin",
+			target.EmailAddress,
+			target.AccountId,
+		)
+	}
+
+	return nil
 }
 
-func newAccountBuilder(client *tagmanager.Service, accounts []string) *accountBuilder {
+func newAccountBuilder(
+	client *tagmanager.Service,
+	accounts []string,
+	roleResources bool,
+	revokePolicy RevokePolicy,
+	allowLastAdminRemoval bool,
+	policy *ProvisioningPolicy,
+	internalDomains []string,
+	writer *mutationWriter,
+) *accountBuilder {
 	accMap := make(map[string]struct{}, len(accounts))
 	for _, acc := range accounts {
 		accMap[acc] = struct{}{}
 	}
 
 	return &accountBuilder{
-		client:       client,
-		resourceType: accountResourceType,
-		accountMap:   accMap,
+		client:                client,
+		resourceType:          accountResourceType,
+		accountMap:            accMap,
+		roleResources:         roleResources,
+		revokePolicy:          revokePolicy,
+		allowLastAdminRemoval: allowLastAdminRemoval,
+		policy:                policy,
+		internalDomains:       internalDomains,
+		writer:                writer,
 	}
 }
@@ -0,0 +1,32 @@
+package connector
+
+import (
+	"testing"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+func TestAccountAccessNeedsChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		up         *tagmanager.UserPermission
+		permission string
+		revoke     bool
+		want       bool
+	}{
+		{"grant without account access", &tagmanager.UserPermission{}, AdminRole, false, true},
+		{"grant different permission", &tagmanager.UserPermission{AccountAccess: &tagmanager.AccountAccess{Permission: UserRole}}, AdminRole, false, true},
+		{"grant same permission", &tagmanager.UserPermission{AccountAccess: &tagmanager.AccountAccess{Permission: AdminRole}}, AdminRole, false, false},
+		{"revoke without account access", &tagmanager.UserPermission{}, AdminRole, true, false},
+		{"revoke different permission", &tagmanager.UserPermission{AccountAccess: &tagmanager.AccountAccess{Permission: UserRole}}, AdminRole, true, false},
+		{"revoke same permission", &tagmanager.UserPermission{AccountAccess: &tagmanager.AccountAccess{Permission: AdminRole}}, AdminRole, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := accountAccessNeedsChange(tt.up, tt.permission, tt.revoke); got != tt.want {
+				t.Errorf("accountAccessNeedsChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"google.golang.org/api/tagmanager/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	assetKindContainerVersion = "container_version"
+	assetKindContainerSnippet = "container_snippet"
+
+	contentTypeJSON = "application/json"
+	contentTypeHTML = "text/html; charset=utf-8"
+)
+
+// newContainerVersionAssetRef returns a reference to the full JSON export of the container version.
+func newContainerVersionAssetRef(accID, containerID, versionID string) *v2.AssetRef {
+	return &v2.AssetRef{
+		Id: encodeCompositeID(assetKindContainerVersion, accID, containerID, versionID),
+	}
+}
+
+// newContainerSnippetAssetRef returns a reference to the install snippet of the container.
+func newContainerSnippetAssetRef(accID, containerID string) *v2.AssetRef {
+	return &v2.AssetRef{
+		Id: encodeCompositeID(assetKindContainerSnippet, accID, containerID),
+	}
+}
+
+// fetchAsset returns the content type and content of the asset the id refers to.
+func fetchAsset(ctx context.Context, client *tagmanager.Service, assetID string) (string, io.ReadCloser, error) {
+	parts, err := decodeCompositeID(assetID, -1)
+	if err != nil {
+		return "", nil, status.Errorf(codes.InvalidArgument, "googletagmanager-connector: invalid asset id: %s", assetID)
+	}
+
+	switch parts[0] {
+	case assetKindContainerVersion:
+		if len(parts) != 4 {
+			return "", nil, status.Errorf(codes.InvalidArgument, "googletagmanager-connector: invalid container version asset id: %s", assetID)
+		}
+
+		return fetchContainerVersionAsset(ctx, client, parts[1], parts[2], parts[3])
+	case assetKindContainerSnippet:
+		if len(parts) != 3 {
+			return "", nil, status.Errorf(codes.InvalidArgument, "googletagmanager-connector: invalid container snippet asset id: %s", assetID)
+		}
+
+		return fetchContainerSnippetAsset(ctx, client, parts[1], parts[2])
+	default:
+		return "", nil, status.Errorf(codes.NotFound, "googletagmanager-connector: unknown asset kind: %s", parts[0])
+	}
+}
+
+func fetchContainerVersionAsset(ctx context.Context, client *tagmanager.Service, accID, containerID, versionID string) (string, io.ReadCloser, error) {
+	vPath := fmt.Sprintf("accounts/%s/containers/%s/versions/%s", accID, containerID, versionID)
+	cv, err := client.Accounts.Containers.Versions.Get(vPath).Context(ctx).Do()
+	if err != nil {
+		return "", nil, fmt.Errorf("googletagmanager-connector: failed to get container version: %w", err)
+	}
+
+	data, err := json.Marshal(cv)
+	if err != nil {
+		return "", nil, fmt.Errorf("googletagmanager-connector: failed to marshal container version: %w", err)
+	}
+
+	return contentTypeJSON, io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func fetchContainerSnippetAsset(ctx context.Context, client *tagmanager.Service, accID, containerID string) (string, io.ReadCloser, error) {
+	cPath := fmt.Sprintf("accounts/%s/containers/%s", accID, containerID)
+	snippet, err := client.Accounts.Containers.Snippet(cPath).Context(ctx).Do()
+	if err != nil {
+		return "", nil, fmt.Errorf("googletagmanager-connector: failed to get container snippet: %w", err)
+	}
+
+	return contentTypeHTML, io.NopCloser(strings.NewReader(snippet.Snippet)), nil
+}
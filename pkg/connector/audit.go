@@ -0,0 +1,144 @@
+package connector
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+const (
+	auditOutcomeSuccess = "success"
+	auditOutcomeFailure = "failure"
+)
+
+// AuditLog appends JSON lines recording the outcome of every mutation made by the connector to a file.
+type AuditLog struct {
+	path  string
+	actor string
+	mu    sync.Mutex
+}
+
+type auditRecord struct {
+	Timestamp               time.Time                     `json:"timestamp"`
+	Actor                   string                        `json:"actor"`
+	Operation               string                        `json:"operation"`
+	Path                    string                        `json:"path"`
+	AccountID               string                        `json:"account_id"`
+	ResourceType            string                        `json:"resource_type,omitempty"`
+	ResourceName            string                        `json:"resource_name,omitempty"`
+	Email                   string                        `json:"email"`
+	PreviousAccountAccess   *tagmanager.AccountAccess     `json:"previous_account_access"`
+	NewAccountAccess        *tagmanager.AccountAccess     `json:"new_account_access"`
+	PreviousContainerAccess []*tagmanager.ContainerAccess `json:"previous_container_access"`
+	NewContainerAccess      []*tagmanager.ContainerAccess `json:"new_container_access"`
+	Outcome                 string                        `json:"outcome"`
+	Error                   string                        `json:"error,omitempty"`
+}
+
+// NewAuditLog returns an audit log appending to the file at path, recording actor as the identity making the changes.
+func NewAuditLog(path, actor string) (*AuditLog, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to open audit log: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to close audit log: %w", err)
+	}
+
+	return &AuditLog{
+		path:  path,
+		actor: actor,
+	}, nil
+}
+
+// permissionAuditRecord describes a user permission mutation, after is nil for deletes.
+func permissionAuditRecord(operation, pPath string, before, after *tagmanager.UserPermission) auditRecord {
+	r := auditRecord{
+		Operation: operation,
+		Path:      pPath,
+	}
+
+	if before != nil {
+		r.AccountID = before.AccountId
+		r.Email = before.EmailAddress
+		r.PreviousAccountAccess = before.AccountAccess
+		r.PreviousContainerAccess = before.ContainerAccess
+	}
+
+	if after != nil {
+		r.AccountID = after.AccountId
+		r.Email = after.EmailAddress
+		r.NewAccountAccess = after.AccountAccess
+		r.NewContainerAccess = after.ContainerAccess
+	}
+
+	return r
+}
+
+// resourceAuditRecord describes the creation or deletion of a resource such as a container, rPath is the parent path for creates.
+func resourceAuditRecord(operation, rPath, resourceType, name string) auditRecord {
+	return auditRecord{
+		Operation:    operation,
+		Path:         rPath,
+		AccountID:    accountIDFromPath(rPath),
+		ResourceType: resourceType,
+		ResourceName: name,
+	}
+}
+
+// record appends the outcome of the mutation described by r.
+func (a *AuditLog) record(r auditRecord, outcome string, opErr error) error {
+	if a == nil {
+		return nil
+	}
+
+	r.Timestamp = time.Now().UTC()
+	r.Actor = a.actor
+	r.Outcome = outcome
+	if opErr != nil {
+		r.Error = opErr.Error()
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("googletagmanager-connector: failed to marshal audit record: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, err := os.OpenFile(a.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("googletagmanager-connector: failed to open audit log: %w", err)
+	}
+
+	_, err = f.Write(append(line, '\n'))
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("googletagmanager-connector: failed to write audit log: %w", err)
+	}
+
+	err = f.Close()
+	if err != nil {
+		return fmt.Errorf("googletagmanager-connector: failed to close audit log: %w", err)
+	}
+
+	return nil
+}
+
+// accountIDFromPath returns the account id of a Tag Manager path such as accounts/1/containers/2.
+func accountIDFromPath(p string) string {
+	parts := strings.Split(p, "/")
+	if len(parts) < 2 || parts[0] != "accounts" {
+		return ""
+	}
+
+	return parts[1]
+}
@@ -9,28 +9,164 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
-	"google.golang.org/api/option"
 	"google.golang.org/api/tagmanager/v2"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
 )
 
 type GoogleTagManager struct {
-	accounts []string
-	client   *tagmanager.Service
+	accounts              []string
+	client                *tagmanager.Service
+	roleResources         bool
+	revokePolicy          RevokePolicy
+	allowLastAdminRemoval bool
+	policy                *ProvisioningPolicy
+	internalDomains       []string
+	dryRun                bool
+	auditLog              *AuditLog
+	allowContainerDelete  bool
+	syncWorkspaces        bool
+	syncDestinations      bool
+	syncCustomTemplates   bool
+	containerAssets       bool
+}
+
+// Option configures optional behaviour of the connector.
+type Option func(*GoogleTagManager)
+
+// WithRoleResources makes the connector sync account and container roles as role resources under each account.
+func WithRoleResources(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.roleResources = enabled
+	}
+}
+
+// WithRevokePolicy sets the policy applied when revoking the minimal account access of a user.
+func WithRevokePolicy(policy RevokePolicy) Option {
+	return func(g *GoogleTagManager) {
+		g.revokePolicy = policy
+	}
+}
+
+// WithAllowLastAdminRemoval allows revokes that would leave an account without a human admin.
+func WithAllowLastAdminRemoval(allowed bool) Option {
+	return func(g *GoogleTagManager) {
+		g.allowLastAdminRemoval = allowed
+	}
+}
+
+// WithProvisioningPolicy sets the policy consulted before granting access to an identity.
+func WithProvisioningPolicy(policy *ProvisioningPolicy) Option {
+	return func(g *GoogleTagManager) {
+		g.policy = policy
+	}
+}
+
+// WithInternalDomains sets the email domains of internal identities, users outside of them are flagged as external collaborators.
+func WithInternalDomains(domains []string) Option {
+	return func(g *GoogleTagManager) {
+		g.internalDomains = nil
+		for _, d := range domains {
+			if d = normalizeDomain(d); d != "" {
+				g.internalDomains = append(g.internalDomains, d)
+			}
+		}
+	}
+}
+
+// WithDryRun makes provisioning log the user permission changes and container or workspace creations and deletions it would make instead of applying them.
+func WithDryRun(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.dryRun = enabled
+	}
+}
+
+// WithAuditLog records every user permission, container and workspace mutation made by the connector in the audit log.
+func WithAuditLog(auditLog *AuditLog) Option {
+	return func(g *GoogleTagManager) {
+		g.auditLog = auditLog
+	}
+}
+
+// WithContainerDeletion allows deleting containers through the connector.
+func WithContainerDeletion(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.allowContainerDelete = enabled
+	}
+}
+
+// WithWorkspaces makes the connector sync the workspaces of every container, which takes a workspace list call per container.
+func WithWorkspaces(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.syncWorkspaces = enabled
+	}
+}
+
+// WithDestinations makes the connector sync the Google tag destinations linked to every container,
+// which takes a destination list call per container.
+func WithDestinations(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.syncDestinations = enabled
+	}
+}
+
+// WithCustomTemplates makes the connector sync the custom templates in the live version of every container,
+// which takes a live version call per container.
+func WithCustomTemplates(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.syncCustomTemplates = enabled
+	}
+}
+
+// WithContainerAssets makes the connector reference the live version and install snippet of every container as assets,
+// which takes a live version call per container.
+func WithContainerAssets(enabled bool) Option {
+	return func(g *GoogleTagManager) {
+		g.containerAssets = enabled
+	}
 }
 
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (g *GoogleTagManager) ResourceSyncers(ctx context.Context) []connectorbuilder.ResourceSyncer {
-	return []connectorbuilder.ResourceSyncer{
-		newAccountBuilder(g.client, g.accounts),
-		newContainerBuilder(g.client),
-		newUserBuilder(g.client),
+	containers := newContainerIndex(g.client, g.accounts)
+	writer := &mutationWriter{
+		client:   g.client,
+		dryRun:   g.dryRun,
+		auditLog: g.auditLog,
+	}
+
+	rv := []connectorbuilder.ResourceSyncer{
+		newAccountBuilder(g.client, g.accounts, g.roleResources, g.revokePolicy, g.allowLastAdminRemoval, g.policy, g.internalDomains, writer),
+		newContainerBuilder(g.client, containers, g.policy, writer, g.allowContainerDelete, g.syncWorkspaces, g.syncDestinations, g.syncCustomTemplates, g.containerAssets),
+		newUserBuilder(g.client, g.internalDomains),
 	}
+
+	if g.syncWorkspaces {
+		rv = append(rv, newWorkspaceBuilder(g.client, containers, writer))
+	}
+
+	if g.syncDestinations {
+		rv = append(rv, newDestinationBuilder(g.client, containers))
+	}
+
+	if g.syncCustomTemplates {
+		rv = append(rv, newCustomTemplateBuilder(g.client, containers))
+	}
+
+	if g.roleResources {
+		rv = append(rv,
+			newAccountRoleBuilder(g.client),
+			newContainerRoleBuilder(g.client),
+		)
+	}
+
+	return rv
 }
 
 // Asset takes an input AssetRef and attempts to fetch it using the connector's authenticated http client
 // It streams a response, always starting with a metadata object, following by chunked payloads for the asset.
 func (g *GoogleTagManager) Asset(ctx context.Context, asset *v2.AssetRef) (string, io.ReadCloser, error) {
-	return "", nil, nil
+	return fetchAsset(ctx, g.client, asset.Id)
 }
 
 // Metadata returns metadata about the connector.
@@ -53,19 +189,21 @@ func (d *GoogleTagManager) Validate(ctx context.Context) (annotations.Annotation
 }
 
 // New returns a new instance of the connector.
-func New(ctx context.Context, ac uhttp.AuthCredentials, accounts []string) (*GoogleTagManager, error) {
-	httpClient, err := ac.GetClient(ctx)
+func New(ctx context.Context, ac uhttp.AuthCredentials, accounts []string, opts ...Option) (*GoogleTagManager, error) {
+	tagmanagerService, err := gtm.NewService(ctx, ac)
 	if err != nil {
-		return nil, fmt.Errorf("googletagmanager-connector: error creating http client: %w", err)
+		return nil, fmt.Errorf("googletagmanager-connector: %w", err)
 	}
 
-	tagmanagerService, err := tagmanager.NewService(ctx, option.WithHTTPClient(httpClient))
-	if err != nil {
-		return nil, fmt.Errorf("error creating tagmanager service: %w", err)
+	g := &GoogleTagManager{
+		client:       tagmanagerService,
+		accounts:     accounts,
+		revokePolicy: RevokePolicyDeleteCascade,
 	}
 
-	return &GoogleTagManager{
-		client:   tagmanagerService,
-		accounts: accounts,
-	}, nil
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g, nil
 }
@@ -0,0 +1,108 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"google.golang.org/api/tagmanager/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
+)
+
+// liveVersionFields are the fields of the live versions kept by the index, enough for the version asset and the custom templates.
+const liveVersionFields = "containerVersionId,customTemplate"
+
+// containerIndex resolves container ids to their API path and caches their live version.
+// Container resource ids do not carry the account, but container ids are unique across accounts.
+type containerIndex struct {
+	client   *tagmanager.Service
+	accounts []string
+	mu       sync.Mutex
+	paths    map[string]string
+	versions map[string]*tagmanager.ContainerVersion
+}
+
+// add remembers the path of the container, it is called for every container seen during sync.
+func (i *containerIndex) add(container *tagmanager.Container) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.paths[container.ContainerId] = container.Path
+}
+
+// path returns the API path of the container, looking it up in the synced accounts when it has not been seen yet.
+func (i *containerIndex) path(ctx context.Context, containerID string) (string, error) {
+	i.mu.Lock()
+	cPath, ok := i.paths[containerID]
+	i.mu.Unlock()
+
+	if ok {
+		return cPath, nil
+	}
+
+	err := gtm.WalkContainers(ctx, i.client, i.accounts, func(container *tagmanager.Container) bool {
+		i.add(container)
+
+		if container.ContainerId == containerID {
+			cPath = container.Path
+			return false
+		}
+
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("googletagmanager-connector: failed to find container %s: %w", containerID, err)
+	}
+
+	if cPath != "" {
+		return cPath, nil
+	}
+
+	return "", status.Errorf(codes.NotFound, "googletagmanager-connector: container %s not found", containerID)
+}
+
+// liveVersion returns the live version of the container, or nil when it was never published.
+// It is fetched once per container and shared by the builders that need it.
+func (i *containerIndex) liveVersion(ctx context.Context, containerID string) (*tagmanager.ContainerVersion, error) {
+	i.mu.Lock()
+	cv, ok := i.versions[containerID]
+	i.mu.Unlock()
+
+	if ok {
+		return cv, nil
+	}
+
+	cPath, err := i.path(ctx, containerID)
+	if err != nil {
+		return nil, err
+	}
+
+	cv, err = i.client.Accounts.Containers.Versions.Live(cPath).Fields(liveVersionFields).Context(ctx).Do()
+	if err != nil {
+		if !gtm.IsNotFound(err) {
+			return nil, fmt.Errorf("googletagmanager-connector: failed to get live container version: %w", err)
+		}
+
+		// containers that were never published have no live version
+		cv = nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.versions[containerID] = cv
+
+	return cv, nil
+}
+
+func newContainerIndex(client *tagmanager.Service, accounts []string) *containerIndex {
+	return &containerIndex{
+		client:   client,
+		accounts: accounts,
+		paths:    make(map[string]string),
+		versions: make(map[string]*tagmanager.ContainerVersion),
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"slices"
-	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -15,23 +14,71 @@ import (
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
 	"go.uber.org/zap"
 	"google.golang.org/api/tagmanager/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// webUsageContext is the usage context of containers installed on websites with a snippet.
+const webUsageContext = "web"
+
 type containerBuilder struct {
 	client       *tagmanager.Service
 	resourceType *v2.ResourceType
+	policy       *ProvisioningPolicy
+	writer       *mutationWriter
+	containers   *containerIndex
+	allowDelete  bool
+	workspaces   bool
+	destinations bool
+	templates    bool
+	assets       bool
 }
 
 func (c *containerBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return containerResourceType
 }
 
-func containerResource(ctx context.Context, container *tagmanager.Container, parent *v2.ResourceId) (*v2.Resource, error) {
-	resource, err := rs.NewResource(
+// childResourceTypes returns the enabled resource types synced under each container.
+func (c *containerBuilder) childResourceTypes() []*v2.ResourceType {
+	var rv []*v2.ResourceType
+
+	if c.templates {
+		rv = append(rv, customTemplateResourceType)
+	}
+
+	if c.destinations {
+		rv = append(rv, destinationResourceType)
+	}
+
+	if c.workspaces {
+		rv = append(rv, workspaceResourceType)
+	}
+
+	return rv
+}
+
+func containerResource(ctx context.Context, container *tagmanager.Container, parent *v2.ResourceId, childTypes []*v2.ResourceType, opts ...rs.ResourceOption) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"usage_context": profileList(container.UsageContext),
+		"domain_name":   profileList(container.DomainName),
+		"tag_ids":       profileList(container.TagIds),
+	}
+
+	opts = append(opts,
+		rs.WithParentResourceID(parent),
+		rs.WithDescription(container.Notes),
+	)
+
+	for _, rt := range childTypes {
+		opts = append(opts, rs.WithAnnotation(&v2.ChildResourceType{ResourceTypeId: rt.Id}))
+	}
+
+	resource, err := rs.NewAppResource(
 		container.Name,
 		containerResourceType,
 		container.ContainerId,
-		rs.WithParentResourceID(parent),
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		opts...,
 	)
 
 	if err != nil {
@@ -66,7 +113,17 @@ func (c *containerBuilder) List(ctx context.Context, parentResourceID *v2.Resour
 
 	var rv []*v2.Resource
 	for _, container := range cl.Container {
-		cr, err := containerResource(ctx, container, parentResourceID)
+		c.containers.add(container)
+
+		var opts []rs.ResourceOption
+		if c.assets {
+			opts, err = c.assetRefs(ctx, container)
+			if err != nil {
+				return nil, "", nil, err
+			}
+		}
+
+		cr, err := containerResource(ctx, container, parentResourceID, c.childResourceTypes(), opts...)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -82,6 +139,25 @@ func (c *containerBuilder) List(ctx context.Context, parentResourceID *v2.Resour
 	return rv, nextPage, nil, nil
 }
 
+// assetRefs returns the asset references of the live version and, for web containers, the install snippet of the container.
+func (c *containerBuilder) assetRefs(ctx context.Context, container *tagmanager.Container) ([]rs.ResourceOption, error) {
+	var rv []rs.ResourceOption
+	cv, err := c.containers.liveVersion(ctx, container.ContainerId)
+	if err != nil {
+		return nil, err
+	}
+
+	if cv != nil {
+		rv = append(rv, rs.WithAnnotation(newContainerVersionAssetRef(container.AccountId, container.ContainerId, cv.ContainerVersionId)))
+	}
+
+	if slices.Contains(container.UsageContext, webUsageContext) {
+		rv = append(rv, rs.WithAnnotation(newContainerSnippetAssetRef(container.AccountId, container.ContainerId)))
+	}
+
+	return rv, nil
+}
+
 // Entitlements returns slice of entitlements representing all possible permissions user can have on the container.
 func (c *containerBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var rv []*v2.Entitlement
@@ -134,13 +210,17 @@ func (c *containerBuilder) Grants(ctx context.Context, resource *v2.Resource, pT
 				continue
 			}
 
+			if isNoAccessPermission(ca.Permission) {
+				continue
+			}
+
 			if !slices.Contains(containerPermissions, ca.Permission) {
 				l.Warn("found invalid permission during container grant creation", zap.String("permission", ca.Permission))
 
 				continue
 			}
 
-			id := fmt.Sprintf("%s:%s", parentAccID, up.EmailAddress)
+			id := newUserID(parentAccID, up.EmailAddress)
 			principalID, err := rs.NewResourceID(userResourceType, id)
 			if err != nil {
 				return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to create resource id: %w", err)
@@ -160,6 +240,7 @@ func (c *containerBuilder) Grants(ctx context.Context, resource *v2.Resource, pT
 
 func (c *containerBuilder) FindRelevantPermissions(ctx context.Context, accID, containerID, userID, permission string, revoke bool) ([]string, error) {
 	var rv []string
+	found := false
 	pageToken := ""
 	for {
 		parentPath := fmt.Sprintf("accounts/%s", accID)
@@ -174,42 +255,20 @@ func (c *containerBuilder) FindRelevantPermissions(ctx context.Context, accID, c
 			return nil, fmt.Errorf("googletagmanager-connector: failed to list user permissions: %w", err)
 		}
 
-		uParts := strings.Split(userID, ":")
-		if len(uParts) != 2 {
-			return nil, fmt.Errorf("googletagmanager-connector: invalid user id: %s", userID)
+		_, mail, err := parseUserID(userID)
+		if err != nil {
+			return nil, err
 		}
 
-		mail := uParts[1]
 		for _, up := range ups.UserPermission {
-			if up.EmailAddress != mail {
-				continue
-			}
-
-			if revoke && up.ContainerAccess == nil {
+			if !emailsMatch(up.EmailAddress, mail) {
 				continue
 			}
 
-			if !revoke && up.ContainerAccess == nil {
+			found = true
+			if containerAccessNeedsChange(up, containerID, permission, revoke) {
 				rv = append(rv, up.Path)
 			}
-
-			for _, ca := range up.ContainerAccess {
-				if ca.ContainerId != containerID {
-					continue
-				}
-
-				if revoke {
-					if ca.Permission == permission {
-						rv = append(rv, up.Path)
-					}
-				} else {
-					if ca.Permission == permission {
-						continue
-					}
-
-					rv = append(rv, up.Path)
-				}
-			}
 		}
 
 		if ups.NextPageToken == "" {
@@ -219,6 +278,10 @@ func (c *containerBuilder) FindRelevantPermissions(ctx context.Context, accID, c
 		pageToken = ups.NextPageToken
 	}
 
+	if !revoke && !found {
+		return nil, status.Errorf(codes.NotFound, "googletagmanager-connector: user %s has no access to account %s", userID, accID)
+	}
+
 	return rv, nil
 }
 
@@ -237,6 +300,16 @@ func (c *containerBuilder) Grant(ctx context.Context, principal *v2.Resource, en
 
 	container, userID, permission := entitlement.Resource, principal.Id.Resource, entitlement.Slug
 	accID := container.ParentResourceId.Resource
+	_, mail, err := parseUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.policy.Check(accID, mail)
+	if err != nil {
+		return nil, err
+	}
+
 	pPaths, err := c.FindRelevantPermissions(ctx, accID, container.Id.Resource, userID, permission, false)
 	if err != nil {
 		return nil, err
@@ -260,18 +333,23 @@ func (c *containerBuilder) Grant(ctx context.Context, principal *v2.Resource, en
 		}
 
 		// update existing permission
-		pg.ContainerAccess = append(pg.ContainerAccess,
-			&tagmanager.ContainerAccess{
-				ContainerId: container.Id.Resource,
-				Permission:  permission,
-			},
-		)
+		before := cloneUserPermission(pg)
+		setContainerAccess(pg, container.Id.Resource, permission)
 
 		// update in API
-		_, err = c.client.Accounts.UserPermissions.Update(pPath, pg).Context(ctx).Do()
+		applied, err := c.writer.update(ctx, pPath, before, pg)
 		if err != nil {
 			return nil, fmt.Errorf("googletagmanager-connector: failed to grant permission: %w", err)
 		}
+
+		if !applied {
+			continue
+		}
+
+		_, err = verifyContainerAccess(ctx, c.client, pPath, container.Id.Resource, permission)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return nil, nil
@@ -318,23 +396,140 @@ func (c *containerBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotat
 		}
 
 		// no-access role is used to revoke permissions
-		pg.ContainerAccess = append(pg.ContainerAccess, &tagmanager.ContainerAccess{
-			ContainerId: container.Id.Resource,
-			Permission:  NoAccessRole,
-		})
+		before := cloneUserPermission(pg)
+		setContainerAccess(pg, container.Id.Resource, NoAccessRole)
 
-		_, err = c.client.Accounts.UserPermissions.Update(pPath, pg).Context(ctx).Do()
+		applied, err := c.writer.update(ctx, pPath, before, pg)
 		if err != nil {
 			return nil, fmt.Errorf("googletagmanager-connector: failed to revoke permission: %w", err)
 		}
+
+		if !applied {
+			continue
+		}
+
+		_, err = verifyContainerAccess(ctx, c.client, pPath, container.Id.Resource, NoAccessRole)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return nil, nil
+}
+
+// Create creates a container under the parent account of the resource.
+// The name is taken from the display name and the notes from the description, usage context and domains
+// are read from the usage_context and domain_name lists of the app profile, as synced containers carry.
+// In dry-run mode nothing is created and no resource is returned.
+func (c *containerBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil || resource.ParentResourceId.ResourceType != accountResourceType.Id {
+		return nil, nil, fmt.Errorf("googletagmanager-connector: containers can only be created under an account")
+	}
+
+	container := &tagmanager.Container{
+		Name:  resource.DisplayName,
+		Notes: resource.Description,
+	}
+
+	appTrait := &v2.AppTrait{}
+	annos := annotations.Annotations(resource.Annotations)
+	ok, err := annos.Pick(appTrait)
+	if err != nil {
+		return nil, nil, fmt.Errorf("googletagmanager-connector: failed to read container app trait: %w", err)
+	}
+
+	if ok && appTrait.Profile != nil {
+		container.UsageContext = structStrings(appTrait.Profile, "usage_context")
+		container.DomainName = structStrings(appTrait.Profile, "domain_name")
+	}
+
+	parentPath := fmt.Sprintf("accounts/%s", resource.ParentResourceId.Resource)
+	created, err := c.writer.createContainer(ctx, parentPath, container)
+	if err != nil {
+		return nil, nil, fmt.Errorf("googletagmanager-connector: failed to create container: %w", err)
+	}
+
+	if created == nil {
+		return nil, nil, nil
+	}
+
+	cr, err := containerResource(ctx, created, resource.ParentResourceId, c.childResourceTypes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cr, nil, nil
+}
+
+// Delete deletes the container, it is only allowed when container deletion is explicitly enabled.
+func (c *containerBuilder) Delete(ctx context.Context, resourceID *v2.ResourceId) (annotations.Annotations, error) {
+	if !c.allowDelete {
+		return nil, status.Errorf(codes.FailedPrecondition, "googletagmanager-connector: container deletion is not enabled")
+	}
+
+	cPath, err := c.containers.path(ctx, resourceID.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.writer.deleteContainer(ctx, cPath)
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to delete container: %w", err)
 	}
 
 	return nil, nil
 }
 
-func newContainerBuilder(client *tagmanager.Service) *containerBuilder {
+// setContainerAccess sets the permission for the container on the user permission,
+// replacing an existing entry for the container (including a no-access one) instead of duplicating it.
+// containerAccessNeedsChange reports whether granting or revoking permission on
+// the container changes the user permission. A grant also applies to users
+// that have access to other containers in the account but not to this one.
+func containerAccessNeedsChange(up *tagmanager.UserPermission, containerID, permission string, revoke bool) bool {
+	for _, ca := range up.ContainerAccess {
+		if ca.ContainerId == containerID {
+			return (ca.Permission == permission) == revoke
+		}
+	}
+
+	return !revoke
+}
+
+func setContainerAccess(up *tagmanager.UserPermission, containerID, permission string) {
+	for _, ca := range up.ContainerAccess {
+		if ca.ContainerId == containerID {
+			ca.Permission = permission
+			return
+		}
+	}
+
+	up.ContainerAccess = append(up.ContainerAccess, &tagmanager.ContainerAccess{
+		ContainerId: containerID,
+		Permission:  permission,
+	})
+}
+
+func newContainerBuilder(
+	client *tagmanager.Service,
+	containers *containerIndex,
+	policy *ProvisioningPolicy,
+	writer *mutationWriter,
+	allowDelete bool,
+	workspaces bool,
+	destinations bool,
+	templates bool,
+	assets bool,
+) *containerBuilder {
 	return &containerBuilder{
 		client:       client,
 		resourceType: containerResourceType,
+		policy:       policy,
+		writer:       writer,
+		containers:   containers,
+		allowDelete:  allowDelete,
+		workspaces:   workspaces,
+		destinations: destinations,
+		templates:    templates,
+		assets:       assets,
 	}
 }
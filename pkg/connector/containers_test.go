@@ -0,0 +1,114 @@
+package connector
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+func TestContainerAccessNeedsChange(t *testing.T) {
+	otherContainers := &tagmanager.UserPermission{
+		ContainerAccess: []*tagmanager.ContainerAccess{
+			{ContainerId: "1", Permission: "publish"},
+			{ContainerId: "2", Permission: "read"},
+		},
+	}
+	withContainer := &tagmanager.UserPermission{
+		ContainerAccess: []*tagmanager.ContainerAccess{
+			{ContainerId: "1", Permission: "publish"},
+			{ContainerId: "3", Permission: "edit"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		up         *tagmanager.UserPermission
+		permission string
+		revoke     bool
+		want       bool
+	}{
+		{"grant without container access", &tagmanager.UserPermission{}, "edit", false, true},
+		{"grant with other containers only", otherContainers, "edit", false, true},
+		{"grant different permission", withContainer, "publish", false, true},
+		{"grant same permission", withContainer, "edit", false, false},
+		{"revoke without container access", &tagmanager.UserPermission{}, "edit", true, false},
+		{"revoke with other containers only", otherContainers, "edit", true, false},
+		{"revoke different permission", withContainer, "publish", true, false},
+		{"revoke same permission", withContainer, "edit", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := containerAccessNeedsChange(tt.up, "3", tt.permission, tt.revoke); got != tt.want {
+				t.Errorf("containerAccessNeedsChange() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetContainerAccessAddsMissingContainer(t *testing.T) {
+	up := &tagmanager.UserPermission{
+		ContainerAccess: []*tagmanager.ContainerAccess{
+			{ContainerId: "1", Permission: "publish"},
+		},
+	}
+
+	setContainerAccess(up, "3", "edit")
+
+	if len(up.ContainerAccess) != 2 {
+		t.Fatalf("got %d container entries, want 2", len(up.ContainerAccess))
+	}
+
+	if ca := up.ContainerAccess[0]; ca.ContainerId != "1" || ca.Permission != "publish" {
+		t.Errorf("existing entry changed to %s/%s", ca.ContainerId, ca.Permission)
+	}
+
+	if ca := up.ContainerAccess[1]; ca.ContainerId != "3" || ca.Permission != "edit" {
+		t.Errorf("added entry = %s/%s, want 3/edit", ca.ContainerId, ca.Permission)
+	}
+}
+
+func TestContainerResourceChildTypes(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *containerBuilder
+		want    []string
+	}{
+		{"nothing enabled", &containerBuilder{}, nil},
+		{"custom templates", &containerBuilder{templates: true}, []string{customTemplateResourceType.Id}},
+		{"destinations", &containerBuilder{destinations: true}, []string{destinationResourceType.Id}},
+		{
+			"everything enabled",
+			&containerBuilder{templates: true, destinations: true, workspaces: true},
+			[]string{customTemplateResourceType.Id, destinationResourceType.Id, workspaceResourceType.Id},
+		},
+	}
+
+	container := &tagmanager.Container{ContainerId: "3", Name: "web"}
+	parent := &v2.ResourceId{ResourceType: accountResourceType.Id, Resource: "1"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, err := containerResource(context.Background(), container, parent, tt.builder.childResourceTypes())
+			if err != nil {
+				t.Fatalf("containerResource() error = %v", err)
+			}
+
+			var got []string
+			for _, a := range annotations.Annotations(resource.Annotations) {
+				ct := &v2.ChildResourceType{}
+				if a.UnmarshalTo(ct) == nil {
+					got = append(got, ct.ResourceTypeId)
+				}
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("child resource types = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
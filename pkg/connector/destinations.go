@@ -0,0 +1,143 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/api/tagmanager/v2"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
+)
+
+// destinationPageSize is the number of destinations returned per page when the sync does not ask for a size.
+const destinationPageSize = 50
+
+type destinationBuilder struct {
+	client       *tagmanager.Service
+	resourceType *v2.ResourceType
+	containers   *containerIndex
+}
+
+func (d *destinationBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return destinationResourceType
+}
+
+func destinationResource(ctx context.Context, destination *tagmanager.Destination, parent *v2.ResourceId) (*v2.Resource, error) {
+	destinationID := encodeCompositeID(destination.AccountId, destination.ContainerId, destination.DestinationLinkId)
+	profile := map[string]interface{}{
+		"destination_id":      destination.DestinationId,
+		"destination_link_id": destination.DestinationLinkId,
+	}
+
+	resource, err := rs.NewAppResource(
+		destination.Name,
+		destinationResourceType,
+		destinationID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithParentResourceID(parent),
+		rs.WithDescription(fmt.Sprintf("Google tag destination %s linked to container %s", destination.DestinationId, destination.ContainerId)),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns all the destinations linked to the parent container as resource objects.
+// The API returns every destination at once, they are paged by offset so pages keep the size of the other builders.
+func (d *destinationBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: destinationResourceType.Id})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to parse page token: %w", err)
+	}
+
+	offset := 0
+	if page != "" {
+		offset, err = strconv.Atoi(page)
+		if err != nil || offset < 0 {
+			return nil, "", nil, fmt.Errorf("googletagmanager-connector: invalid destination page token: %s", page)
+		}
+	}
+
+	parentPath, err := d.containers.path(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	dl, err := d.client.Accounts.Containers.Destinations.List(parentPath).Context(ctx).Do()
+	if err != nil {
+		// Containers without Google tag destinations, or that the service account can't read them on, have none to sync.
+		if gtm.IsNotFound(err) || gtm.IsPermissionDenied(err) {
+			ctxzap.Extract(ctx).Debug(
+				"googletagmanager-connector: skipping destinations of container",
+				zap.String("container", parentPath),
+				zap.Error(err),
+			)
+
+			return nil, "", nil, nil
+		}
+
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to list destinations: %w", err)
+	}
+
+	pageSize := pToken.Size
+	if pageSize <= 0 {
+		pageSize = destinationPageSize
+	}
+
+	end := min(offset+pageSize, len(dl.Destination))
+	offset = min(offset, end)
+
+	var rv []*v2.Resource
+	for _, destination := range dl.Destination[offset:end] {
+		dr, err := destinationResource(ctx, destination, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, dr)
+	}
+
+	nextPage := ""
+	if end < len(dl.Destination) {
+		nextPage = strconv.Itoa(end)
+	}
+
+	nextPage, err = bag.NextToken(nextPage)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to set next page token: %w", err)
+	}
+
+	return rv, nextPage, nil, nil
+}
+
+// Entitlements always returns an empty slice for destinations.
+func (d *destinationBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for destinations since they don't have any entitlements.
+func (d *destinationBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+func newDestinationBuilder(client *tagmanager.Service, containers *containerIndex) *destinationBuilder {
+	return &destinationBuilder{
+		client:       client,
+		resourceType: destinationResourceType,
+		containers:   containers,
+	}
+}
@@ -1,12 +1,21 @@
 package connector
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/api/tagmanager/v2"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
-func annotationsForUserResourceType() annotations.Annotations {
+// annotationsForSkippedResourceType marks resource types without entitlements or grants, so syncing skips them.
+func annotationsForSkippedResourceType() annotations.Annotations {
 	annos := annotations.Annotations{}
 	annos.Update(&v2.SkipEntitlementsAndGrants{})
 	return annos
@@ -28,3 +37,84 @@ func parsePageToken(i string, resourceID *v2.ResourceId) (*pagination.Bag, strin
 
 	return b, b.PageToken(), nil
 }
+
+// isNoAccessPermission reports whether the permission represents the absence of access.
+func isNoAccessPermission(permission string) bool {
+	return permission == "" || slices.Contains(noAccessPermissions, permission)
+}
+
+// grantsForUserPermission returns all grants represented by the user permission,
+// the account permission as well as every container permission under the account.
+func grantsForUserPermission(up *tagmanager.UserPermission) ([]*v2.Grant, error) {
+	principalID, err := rs.NewResourceID(userResourceType, newUserID(up.AccountId, up.EmailAddress))
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to create resource id: %w", err)
+	}
+
+	accountID := &v2.ResourceId{ResourceType: accountResourceType.Id, Resource: up.AccountId}
+
+	var rv []*v2.Grant
+	if up.AccountAccess != nil && slices.Contains(accountPermissions, up.AccountAccess.Permission) {
+		rv = append(rv, grant.NewGrant(&v2.Resource{Id: accountID}, up.AccountAccess.Permission, principalID))
+	}
+
+	for _, ca := range up.ContainerAccess {
+		if !slices.Contains(containerPermissions, ca.Permission) {
+			continue
+		}
+
+		container := &v2.Resource{
+			Id:               &v2.ResourceId{ResourceType: containerResourceType.Id, Resource: ca.ContainerId},
+			ParentResourceId: accountID,
+		}
+		rv = append(rv, grant.NewGrant(container, ca.Permission, principalID))
+	}
+
+	return rv, nil
+}
+
+// isHumanPrincipal reports whether the email belongs to a person rather than a Google service account.
+func isHumanPrincipal(email string) bool {
+	return !strings.HasSuffix(strings.ToLower(email), ".gserviceaccount.com")
+}
+
+// isExternalPrincipal reports whether the email is outside the internal domains.
+// Without internal domains configured nobody is considered external.
+func isExternalPrincipal(internalDomains []string, email string) bool {
+	if len(internalDomains) == 0 {
+		return false
+	}
+
+	return !slices.Contains(internalDomains, emailDomain(email))
+}
+
+// structStrings returns the string values of the field of the struct, accepting a list or a single string.
+func structStrings(s *structpb.Struct, field string) []string {
+	v, ok := s.GetFields()[field]
+	if !ok {
+		return nil
+	}
+
+	if str, ok := v.Kind.(*structpb.Value_StringValue); ok {
+		return []string{str.StringValue}
+	}
+
+	var rv []string
+	for _, item := range v.GetListValue().GetValues() {
+		if str, ok := item.Kind.(*structpb.Value_StringValue); ok {
+			rv = append(rv, str.StringValue)
+		}
+	}
+
+	return rv
+}
+
+// profileList converts the values to the list type accepted in resource profiles.
+func profileList(values []string) []interface{} {
+	rv := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		rv = append(rv, v)
+	}
+
+	return rv
+}
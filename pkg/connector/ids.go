@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// compositeIDVersion prefixes resource ids composed of several parts.
+// Ids without it are legacy ids joining the parts with a colon without escaping.
+const compositeIDVersion = "v1"
+
+var compositeIDEscaper = strings.NewReplacer("%", "%25", ":", "%3A")
+
+// encodeCompositeID joins the parts into a versioned resource id, escaping the separator within the parts.
+func encodeCompositeID(parts ...string) string {
+	escaped := make([]string, 0, len(parts)+1)
+	escaped = append(escaped, compositeIDVersion)
+	for _, p := range parts {
+		escaped = append(escaped, compositeIDEscaper.Replace(p))
+	}
+
+	return strings.Join(escaped, ":")
+}
+
+// decodeCompositeID splits a versioned resource id created by encodeCompositeID into its expected number of parts.
+// A negative number of parts accepts any number of them.
+func decodeCompositeID(id string, n int) ([]string, error) {
+	rest, ok := strings.CutPrefix(id, compositeIDVersion+":")
+	if !ok {
+		return nil, fmt.Errorf("googletagmanager-connector: unsupported resource id version: %s", id)
+	}
+
+	escaped := strings.Split(rest, ":")
+	if n >= 0 && len(escaped) != n {
+		return nil, fmt.Errorf("googletagmanager-connector: expected %d parts in resource id: %s", n, id)
+	}
+
+	parts := make([]string, 0, len(escaped))
+	for _, e := range escaped {
+		p, err := url.PathUnescape(e)
+		if err != nil {
+			return nil, fmt.Errorf("googletagmanager-connector: invalid escaping in resource id %s: %w", id, err)
+		}
+
+		parts = append(parts, p)
+	}
+
+	return parts, nil
+}
+
+// isCompositeID reports whether the id was created by encodeCompositeID rather than being a legacy id.
+func isCompositeID(id string) bool {
+	return strings.HasPrefix(id, compositeIDVersion+":")
+}
@@ -0,0 +1,75 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ProvisioningPolicy restricts the identities that can be granted access through the connector.
+type ProvisioningPolicy struct {
+	allowedDomains []string
+	accountDomains map[string][]string
+}
+
+// NewProvisioningPolicy returns a policy allowing the given email domains on every account.
+// Account overrides are given as accountID:domain pairs and replace the allowed domains for that account.
+// A policy without any domains allows every identity.
+func NewProvisioningPolicy(allowedDomains []string, accountOverrides []string) (*ProvisioningPolicy, error) {
+	p := &ProvisioningPolicy{
+		accountDomains: make(map[string][]string),
+	}
+
+	for _, d := range allowedDomains {
+		if d = normalizeDomain(d); d != "" {
+			p.allowedDomains = append(p.allowedDomains, d)
+		}
+	}
+
+	for _, o := range accountOverrides {
+		accID, domain, ok := strings.Cut(o, ":")
+		accID, domain = strings.TrimSpace(accID), normalizeDomain(domain)
+		if !ok || accID == "" || domain == "" {
+			return nil, fmt.Errorf("googletagmanager-connector: invalid account domain override %q, expected accountID:domain", o)
+		}
+
+		p.accountDomains[accID] = append(p.accountDomains[accID], domain)
+	}
+
+	return p, nil
+}
+
+// Check returns a policy violation error when the email is not in a domain allowed on the account.
+func (p *ProvisioningPolicy) Check(accountID, email string) error {
+	if p == nil {
+		return nil
+	}
+
+	allowed, ok := p.accountDomains[accountID]
+	if !ok {
+		allowed = p.allowedDomains
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	if domain := emailDomain(email); domain != "" && slices.Contains(allowed, domain) {
+		return nil
+	}
+
+	return status.Errorf(
+		codes.PermissionDenied,
+		"googletagmanager-connector: policy violation: %s is not in a domain allowed on account %s, allowed domains: %s",
+		email,
+		accountID,
+		strings.Join(allowed, ", "),
+	)
+}
+
+func normalizeDomain(domain string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(domain)), "@")
+}
@@ -10,18 +10,57 @@ var (
 		Id:          "user",
 		DisplayName: "User",
 		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
-		Annotations: annotationsForUserResourceType(),
+		Annotations: annotationsForSkippedResourceType(),
 	}
 
 	// The account resource type is for all account objects from the database.
 	accountResourceType = &v2.ResourceType{
 		Id:          "account",
 		DisplayName: "Account",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
 	}
 
 	// The container resource type is for all container objects from the database.
 	containerResourceType = &v2.ResourceType{
 		Id:          "container",
 		DisplayName: "Container",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+	}
+
+	// The workspace resource type is for all workspace objects of a container.
+	workspaceResourceType = &v2.ResourceType{
+		Id:          "workspace",
+		DisplayName: "Workspace",
+		Annotations: annotationsForSkippedResourceType(),
+	}
+
+	// The destination resource type is for the Google tag destinations linked to a container.
+	destinationResourceType = &v2.ResourceType{
+		Id:          "destination",
+		DisplayName: "Destination",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+		Annotations: annotationsForSkippedResourceType(),
+	}
+
+	// The custom template resource type is for the custom templates in the live version of a container.
+	customTemplateResourceType = &v2.ResourceType{
+		Id:          "custom_template",
+		DisplayName: "Custom Template",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_APP},
+		Annotations: annotationsForSkippedResourceType(),
+	}
+
+	// The account role resource type is for the roles a user can hold on an account.
+	accountRoleResourceType = &v2.ResourceType{
+		Id:          "account_role",
+		DisplayName: "Account Role",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
+	}
+
+	// The container role resource type is for the roles a user can hold on containers of an account.
+	containerRoleResourceType = &v2.ResourceType{
+		Id:          "container_role",
+		DisplayName: "Container Role",
+		Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
 	}
 )
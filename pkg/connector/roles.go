@@ -0,0 +1,180 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	ent "github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+const roleMembership = "member"
+
+type roleBuilder struct {
+	client       *tagmanager.Service
+	resourceType *v2.ResourceType
+	roles        []string
+	// permissionsOf returns the permissions the user permission holds for the roles of this builder.
+	permissionsOf func(up *tagmanager.UserPermission) []string
+}
+
+func (r *roleBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return r.resourceType
+}
+
+func roleResource(ctx context.Context, role string, resourceType *v2.ResourceType, parent *v2.ResourceId) (*v2.Resource, error) {
+	roleID := encodeCompositeID(parent.Resource, role)
+	resource, err := rs.NewRoleResource(
+		fmt.Sprintf("%s %s", role, strings.ToLower(resourceType.DisplayName)),
+		resourceType,
+		roleID,
+		[]rs.RoleTraitOption{
+			rs.WithRoleProfile(map[string]interface{}{
+				"account_id": parent.Resource,
+				"role":       role,
+			}),
+		},
+		rs.WithParentResourceID(parent),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the roles available under the parent account as resource objects.
+func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	var rv []*v2.Resource
+	for _, role := range r.roles {
+		rr, err := roleResource(ctx, role, r.resourceType, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, rr)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements returns a membership entitlement for the role.
+func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return []*v2.Entitlement{
+		ent.NewAssignmentEntitlement(
+			resource,
+			roleMembership,
+			ent.WithGrantableTo(userResourceType),
+			ent.WithDisplayName(fmt.Sprintf("%s member", resource.DisplayName)),
+			ent.WithDescription(fmt.Sprintf("Member of the %s role in GoogleTagManager", resource.DisplayName)),
+		),
+	}, "", nil, nil
+}
+
+// Grants returns a membership grant for every user holding the role under the parent account.
+func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	bag, page, err := parsePageToken(pToken.Token, resource.Id)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to parse page token: %w", err)
+	}
+
+	roleTrait, err := rs.GetRoleTrait(resource)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to get role trait: %w", err)
+	}
+
+	accID, ok := rs.GetProfileStringValue(roleTrait.Profile, "account_id")
+	if !ok {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: missing account id in role profile: %s", resource.Id.Resource)
+	}
+
+	role, ok := rs.GetProfileStringValue(roleTrait.Profile, "role")
+	if !ok {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: missing role in role profile: %s", resource.Id.Resource)
+	}
+
+	parentPath := fmt.Sprintf("accounts/%s", accID)
+	upl := r.client.Accounts.UserPermissions.List(parentPath).Context(ctx)
+
+	if page != "" {
+		upl = upl.PageToken(page)
+	}
+
+	ul, err := upl.Do()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to list user permissions: %w", err)
+	}
+
+	var rv []*v2.Grant
+	for _, up := range ul.UserPermission {
+		for _, perm := range r.permissionsOf(up) {
+			if perm != role {
+				continue
+			}
+
+			id := newUserID(accID, up.EmailAddress)
+			principalID, err := rs.NewResourceID(userResourceType, id)
+			if err != nil {
+				return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to create resource id: %w", err)
+			}
+
+			rv = append(rv, grant.NewGrant(resource, roleMembership, principalID))
+
+			// a user is a member of the role once, regardless of how many containers grant it
+			break
+		}
+	}
+
+	nextPage, err := bag.NextToken(ul.NextPageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to set next page token: %w", err)
+	}
+
+	return rv, nextPage, nil, nil
+}
+
+func accountPermissionsOf(up *tagmanager.UserPermission) []string {
+	if up.AccountAccess == nil {
+		return nil
+	}
+
+	return []string{up.AccountAccess.Permission}
+}
+
+func containerPermissionsOf(up *tagmanager.UserPermission) []string {
+	rv := make([]string, 0, len(up.ContainerAccess))
+	for _, ca := range up.ContainerAccess {
+		rv = append(rv, ca.Permission)
+	}
+
+	return rv
+}
+
+func newAccountRoleBuilder(client *tagmanager.Service) *roleBuilder {
+	return &roleBuilder{
+		client:        client,
+		resourceType:  accountRoleResourceType,
+		roles:         accountPermissions,
+		permissionsOf: accountPermissionsOf,
+	}
+}
+
+func newContainerRoleBuilder(client *tagmanager.Service) *roleBuilder {
+	return &roleBuilder{
+		client:        client,
+		resourceType:  containerRoleResourceType,
+		roles:         containerPermissions,
+		permissionsOf: containerPermissionsOf,
+	}
+}
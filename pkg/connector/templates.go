@@ -0,0 +1,175 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+// webPermissionsSection is the section of the template data declaring the sandboxed JavaScript permissions of a template.
+const webPermissionsSection = "___WEB_PERMISSIONS___"
+
+type customTemplateBuilder struct {
+	client       *tagmanager.Service
+	resourceType *v2.ResourceType
+	containers   *containerIndex
+}
+
+func (t *customTemplateBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return customTemplateResourceType
+}
+
+func customTemplateResource(ctx context.Context, template *tagmanager.CustomTemplate, permissions []string, parent *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"permissions": profileList(permissions),
+	}
+
+	if gr := template.GalleryReference; gr != nil {
+		profile["gallery_host"] = gr.Host
+		profile["gallery_owner"] = gr.Owner
+		profile["gallery_repository"] = gr.Repository
+		profile["gallery_version"] = gr.Version
+		profile["gallery_signature"] = gr.Signature
+		profile["gallery_is_modified"] = gr.IsModified
+	}
+
+	templateID := encodeCompositeID(template.AccountId, template.ContainerId, template.TemplateId)
+	resource, err := rs.NewAppResource(
+		template.Name,
+		customTemplateResourceType,
+		templateID,
+		[]rs.AppTraitOption{rs.WithAppProfile(profile)},
+		rs.WithParentResourceID(parent),
+		rs.WithDescription(fmt.Sprintf("Sandbox permissions: %s", strings.Join(permissions, ", "))),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns the custom templates of the live version of the parent container as resource objects.
+func (t *customTemplateBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	cv, err := t.containers.liveVersion(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	if cv == nil {
+		return nil, "", nil, nil
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	var rv []*v2.Resource
+	for _, template := range cv.CustomTemplate {
+		permissions, err := templatePermissions(template.TemplateData)
+		if err != nil {
+			// one malformed template must not fail the sync, it is emitted without permissions
+			l.Warn(
+				"googletagmanager-connector: failed to parse permissions of custom template",
+				zap.String("template_id", template.TemplateId),
+				zap.String("container_id", template.ContainerId),
+				zap.Error(err),
+			)
+
+			permissions = nil
+		}
+
+		tr, err := customTemplateResource(ctx, template, permissions, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, tr)
+	}
+
+	return rv, "", nil, nil
+}
+
+// Entitlements always returns an empty slice for custom templates, their sandbox permissions are in the profile.
+func (t *customTemplateBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for custom templates, their permissions are declared rather than granted.
+func (t *customTemplateBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// templatePermissions returns the permissions declared in the web permissions section of the template data.
+func templatePermissions(templateData string) ([]string, error) {
+	section := templateSection(templateData, webPermissionsSection)
+	if strings.TrimSpace(section) == "" {
+		return nil, nil
+	}
+
+	var declared []struct {
+		Instance struct {
+			Key struct {
+				PublicID string `json:"publicId"`
+			} `json:"key"`
+		} `json:"instance"`
+	}
+
+	err := json.Unmarshal([]byte(section), &declared)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]string, 0, len(declared))
+	for _, d := range declared {
+		if d.Instance.Key.PublicID != "" {
+			rv = append(rv, d.Instance.Key.PublicID)
+		}
+	}
+
+	return rv, nil
+}
+
+// templateSection returns the content of the section of the template data, sections start with a ___NAME___ line.
+func templateSection(templateData, name string) string {
+	var b strings.Builder
+	inSection := false
+	for _, line := range strings.Split(templateData, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "___") && strings.HasSuffix(trimmed, "___") && len(trimmed) > 6 {
+			if inSection {
+				break
+			}
+
+			inSection = trimmed == name
+			continue
+		}
+
+		if inSection {
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String()
+}
+
+func newCustomTemplateBuilder(client *tagmanager.Service, containers *containerIndex) *customTemplateBuilder {
+	return &customTemplateBuilder{
+		client:       client,
+		resourceType: customTemplateResourceType,
+		containers:   containers,
+	}
+}
@@ -0,0 +1,108 @@
+package connector
+
+import (
+	"slices"
+	"testing"
+)
+
+const testTemplateData = `___TERMS_OF_SERVICE___
+
+By creating or modifying this file you agree to the terms.
+
+___INFO___
+
+{
+  "type": "TAG",
+  "displayName": "Example"
+}
+
+___WEB_PERMISSIONS___
+
+[
+  {
+    "instance": {
+      "key": {
+        "publicId": "inject_script",
+        "versionId": "1"
+      },
+      "param": []
+    },
+    "isRequired": true
+  },
+  {
+    "instance": {
+      "key": {
+        "publicId": "logging",
+        "versionId": "1"
+      }
+    },
+    "isRequired": true
+  }
+]
+
+___TESTS___
+
+scenarios: []
+`
+
+func TestTemplateSection(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		section string
+		want    string
+	}{
+		{"middle section", testTemplateData, "___INFO___", "\n{\n  \"type\": \"TAG\",\n  \"displayName\": \"Example\"\n}\n\n"},
+		{"last section", testTemplateData, "___TESTS___", "\nscenarios: []\n\n"},
+		{"missing section", testTemplateData, "___SANDBOXED_JS_FOR_WEB_TEMPLATE___", ""},
+		{"indented marker", "  ___INFO___  \nbody\n___TESTS___\n", "___INFO___", "body\n"},
+		{"underscores alone are not a marker", "___INFO___\n______\nbody", "___INFO___", "______\nbody\n"},
+		{"empty data", "", "___INFO___", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := templateSection(tt.data, tt.section); got != tt.want {
+				t.Errorf("templateSection(%q) = %q, want %q", tt.section, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplatePermissions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{"declared permissions", testTemplateData, []string{"inject_script", "logging"}, false},
+		{"no permissions section", "___INFO___\n{}\n", nil, false},
+		{"empty permissions section", "___WEB_PERMISSIONS___\n\n___TESTS___\n", nil, false},
+		{"empty permission list", "___WEB_PERMISSIONS___\n[]\n", []string{}, false},
+		{"entries without public id are skipped", "___WEB_PERMISSIONS___\n[{\"instance\": {\"key\": {}}}, {\"instance\": {\"key\": {\"publicId\": \"read_title\"}}}]\n", []string{"read_title"}, false},
+		{"malformed json", "___WEB_PERMISSIONS___\n[{\"instance\": \n___TESTS___\n", nil, true},
+		{"object instead of list", "___WEB_PERMISSIONS___\n{\"instance\": {}}\n", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templatePermissions(tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("templatePermissions() = %v, want an error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("templatePermissions() returned error: %v", err)
+			}
+
+			if !slices.Equal(got, tt.want) || (got == nil) != (tt.want == nil) {
+				t.Errorf("templatePermissions() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
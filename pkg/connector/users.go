@@ -3,6 +3,7 @@ package connector
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -12,22 +13,110 @@ import (
 )
 
 type userBuilder struct {
-	client       *tagmanager.Service
-	resourceType *v2.ResourceType
+	client          *tagmanager.Service
+	resourceType    *v2.ResourceType
+	internalDomains []string
 }
 
 func (u *userBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
 	return userResourceType
 }
 
-func userResource(ctx context.Context, mail string, parent *v2.ResourceId) (*v2.Resource, error) {
+// newUserID returns the resource id of the user under the account.
+func newUserID(accID, mail string) string {
+	return encodeCompositeID(accID, mail)
+}
+
+// parseUserID returns the account id and email encoded in the user resource id.
+// Legacy ids are accepted as well, account ids never contain a colon so everything after the first one is the email.
+func parseUserID(userID string) (string, string, error) {
+	if isCompositeID(userID) {
+		parts, err := decodeCompositeID(userID, 2)
+		if err != nil {
+			return "", "", err
+		}
+
+		userID = parts[0] + ":" + parts[1]
+	}
+
+	accID, mail, ok := strings.Cut(userID, ":")
+	accID, mail = strings.TrimSpace(accID), strings.TrimSpace(mail)
+	if !ok || accID == "" || !strings.Contains(mail, "@") {
+		return "", "", fmt.Errorf("googletagmanager-connector: invalid user id: %s", userID)
+	}
+
+	return accID, mail, nil
+}
+
+// normalizeEmail returns the canonical form of the email used for comparisons.
+// Emails are compared case-insensitively and googlemail.com is an alias of gmail.com.
+func normalizeEmail(mail string) string {
+	mail = strings.ToLower(strings.TrimSpace(mail))
+
+	// the local part may contain a quoted @, the domain never does
+	at := strings.LastIndex(mail, "@")
+	if at < 0 {
+		return mail
+	}
+
+	local, domain := mail[:at], mail[at+1:]
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+
+	return local + "@" + domain
+}
+
+// emailDomain returns the normalized domain of the email, or an empty string when it has none.
+func emailDomain(mail string) string {
+	mail = normalizeEmail(mail)
+
+	at := strings.LastIndex(mail, "@")
+	if at < 0 {
+		return ""
+	}
+
+	return mail[at+1:]
+}
+
+// emailsMatch reports whether both emails identify the same principal.
+func emailsMatch(a, b string) bool {
+	return normalizeEmail(a) == normalizeEmail(b)
+}
+
+// withExternalCollaboratorStatus keeps the user enabled and records in the
+// status details that the email is outside the internal domains.
+func withExternalCollaboratorStatus(domain string) rs.UserTraitOption {
+	return func(ut *v2.UserTrait) error {
+		ut.Status = &v2.UserTrait_Status{
+			Status:  v2.UserTrait_Status_STATUS_ENABLED,
+			Details: fmt.Sprintf("external collaborator (%s)", domain),
+		}
+
+		return nil
+	}
+}
+
+func userResource(ctx context.Context, mail string, parent *v2.ResourceId, internalDomains []string) (*v2.Resource, error) {
 	userTraitOptions := []rs.UserTraitOption{
 		rs.WithStatus(v2.UserTrait_Status_STATUS_ENABLED),
 		rs.WithEmail(mail, true),
 		rs.WithUserLogin(mail),
 	}
 
-	userID := fmt.Sprintf("%s:%s", parent.Resource, mail)
+	if len(internalDomains) > 0 {
+		external := isExternalPrincipal(internalDomains, mail)
+		userTraitOptions = append(userTraitOptions, rs.WithUserProfile(map[string]interface{}{
+			"external_collaborator": external,
+			"email_domain":          emailDomain(mail),
+		}))
+
+		if external {
+			userTraitOptions = append(userTraitOptions, withExternalCollaboratorStatus(emailDomain(mail)))
+		}
+	}
+
+	userID := newUserID(parent.Resource, mail)
 	resource, err := rs.NewUserResource(
 		mail,
 		userResourceType,
@@ -69,7 +158,7 @@ func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId,
 
 	var rv []*v2.Resource
 	for _, up := range ul.UserPermission {
-		ur, err := userResource(ctx, up.EmailAddress, parentResourceID)
+		ur, err := userResource(ctx, up.EmailAddress, parentResourceID, u.internalDomains)
 		if err != nil {
 			return nil, "", nil, err
 		}
@@ -95,9 +184,10 @@ func (u *userBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken
 	return nil, "", nil, nil
 }
 
-func newUserBuilder(client *tagmanager.Service) *userBuilder {
+func newUserBuilder(client *tagmanager.Service, internalDomains []string) *userBuilder {
 	return &userBuilder{
-		client:       client,
-		resourceType: userResourceType,
+		client:          client,
+		resourceType:    userResourceType,
+		internalDomains: internalDomains,
 	}
 }
@@ -0,0 +1,168 @@
+package connector
+
+import (
+	"context"
+	"testing"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		mail string
+		want string
+	}{
+		{"plain", "jane@example.com", "jane@example.com"},
+		{"mixed case", "Jane.Doe@Example.COM", "jane.doe@example.com"},
+		{"surrounding whitespace", "  jane@example.com\t\n", "jane@example.com"},
+		{"googlemail alias", "jane@googlemail.com", "jane@gmail.com"},
+		{"googlemail alias mixed case", "Jane@GoogleMail.com", "jane@gmail.com"},
+		{"plus addressing is kept", "jane+gtm@example.com", "jane+gtm@example.com"},
+		{"quoted local part with @", `"jane@home"@example.com`, `"jane@home"@example.com`},
+		{"quoted local part with @ on googlemail", `"Jane@Home"@googlemail.com`, `"jane@home"@gmail.com`},
+		{"no domain", "Jane", "jane"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeEmail(tt.mail); got != tt.want {
+				t.Errorf("normalizeEmail(%q) = %q, want %q", tt.mail, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailsMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		a    string
+		b    string
+		want bool
+	}{
+		{"identical", "jane@example.com", "jane@example.com", true},
+		{"mixed case and whitespace", " JANE@example.com ", "jane@EXAMPLE.com", true},
+		{"googlemail alias", "jane@googlemail.com", "jane@gmail.com", true},
+		{"plus addressing is a different principal", "jane+gtm@example.com", "jane@example.com", false},
+		{"plus addressing with same tag", "Jane+GTM@example.com", "jane+gtm@example.com", true},
+		{"quoted local part with @", `"jane@home"@example.com`, `"JANE@home"@Example.com`, true},
+		{"quoted local part differs from domain split", `"jane@home"@example.com`, "jane@home", false},
+		{"different domains", "jane@example.com", "jane@example.org", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := emailsMatch(tt.a, tt.b); got != tt.want {
+				t.Errorf("emailsMatch(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	tests := []struct {
+		mail string
+		want string
+	}{
+		{"jane@Example.com", "example.com"},
+		{"jane@googlemail.com", "gmail.com"},
+		{`"jane@home"@example.com`, "example.com"},
+		{"jane", ""},
+	}
+
+	for _, tt := range tests {
+		if got := emailDomain(tt.mail); got != tt.want {
+			t.Errorf("emailDomain(%q) = %q, want %q", tt.mail, got, tt.want)
+		}
+	}
+}
+
+func TestParseUserID(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		accID   string
+		mail    string
+		wantErr bool
+	}{
+		{"legacy", "123:jane@example.com", "123", "jane@example.com", false},
+		{"legacy plus addressing", "123:jane+gtm@example.com", "123", "jane+gtm@example.com", false},
+		{"legacy quoted local part with @", `123:"jane@home"@example.com`, "123", `"jane@home"@example.com`, false},
+		{"legacy quoted local part with colon", `123:"jane:home"@example.com`, "123", `"jane:home"@example.com`, false},
+		{"legacy surrounding whitespace", " 123 : jane@example.com ", "123", "jane@example.com", false},
+		{"composite", newUserID("123", "jane@example.com"), "123", "jane@example.com", false},
+		{"composite quoted local part with colon", newUserID("123", `"jane:home"@example.com`), "123", `"jane:home"@example.com`, false},
+		{"composite quoted local part with percent", newUserID("123", `"50%off"@example.com`), "123", `"50%off"@example.com`, false},
+		{"composite googlemail", newUserID("123", "Jane@googlemail.com"), "123", "Jane@googlemail.com", false},
+		{"legacy without email", "123:jane", "", "", true},
+		{"legacy without account", ":jane@example.com", "", "", true},
+		{"no separator", "jane@example.com", "", "", true},
+		{"composite with missing part", "v1:123", "", "", true},
+		{"composite with extra part", "v1:123:jane@example.com:extra", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accID, mail, err := parseUserID(tt.userID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseUserID(%q) = %q, %q, want an error", tt.userID, accID, mail)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseUserID(%q) returned error: %v", tt.userID, err)
+			}
+
+			if accID != tt.accID || mail != tt.mail {
+				t.Errorf("parseUserID(%q) = %q, %q, want %q, %q", tt.userID, accID, mail, tt.accID, tt.mail)
+			}
+		})
+	}
+}
+
+func TestUserResourceExternalCollaborator(t *testing.T) {
+	parent := &v2.ResourceId{ResourceType: accountResourceType.Id, Resource: "123"}
+
+	tests := []struct {
+		name            string
+		mail            string
+		internalDomains []string
+		wantExternal    bool
+		wantDetails     string
+	}{
+		{"no internal domains", "jane@gmail.com", nil, false, ""},
+		{"internal user", "jane@example.com", []string{"example.com"}, false, ""},
+		{"external user", "jane@gmail.com", []string{"example.com"}, true, "external collaborator (gmail.com)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource, err := userResource(context.Background(), tt.mail, parent, tt.internalDomains)
+			if err != nil {
+				t.Fatalf("userResource() error = %v", err)
+			}
+
+			trait, err := rs.GetUserTrait(resource)
+			if err != nil {
+				t.Fatalf("GetUserTrait() error = %v", err)
+			}
+
+			if got := trait.GetStatus().GetStatus(); got != v2.UserTrait_Status_STATUS_ENABLED {
+				t.Errorf("status = %v, want enabled", got)
+			}
+
+			if got := trait.GetStatus().GetDetails(); got != tt.wantDetails {
+				t.Errorf("status details = %q, want %q", got, tt.wantDetails)
+			}
+
+			external := trait.GetProfile().GetFields()["external_collaborator"].GetBoolValue()
+			if external != tt.wantExternal {
+				t.Errorf("external_collaborator = %v, want %v", external, tt.wantExternal)
+			}
+		})
+	}
+}
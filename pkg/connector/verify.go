@@ -0,0 +1,121 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/api/tagmanager/v2"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
+)
+
+// verifyAccountAccess re-fetches the user permission and checks that the account permission is in effect.
+func verifyAccountAccess(ctx context.Context, client *tagmanager.Service, pPath, permission string) (*tagmanager.UserPermission, error) {
+	up, err := client.Accounts.UserPermissions.Get(pPath).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to re-fetch permission for verification: %w", err)
+	}
+
+	if up.AccountAccess == nil || up.AccountAccess.Permission != permission {
+		return nil, fmt.Errorf(
+			"googletagmanager-connector: account permission %s not in effect for %s after update, observed %s",
+			permission,
+			up.EmailAddress,
+			describeUserPermission(up),
+		)
+	}
+
+	return up, nil
+}
+
+// verifyContainerAccess re-fetches the user permission and checks that the container permission is in effect.
+// Every entry for the container has to carry the permission, a no-access permission is satisfied by no effective access.
+func verifyContainerAccess(ctx context.Context, client *tagmanager.Service, pPath, containerID, permission string) (*tagmanager.UserPermission, error) {
+	up, err := client.Accounts.UserPermissions.Get(pPath).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to re-fetch permission for verification: %w", err)
+	}
+
+	if isNoAccessPermission(permission) {
+		if hasContainerAccess(up, containerID) {
+			return nil, fmt.Errorf(
+				"googletagmanager-connector: access to container %s still in effect for %s after revoke, observed %s",
+				containerID,
+				up.EmailAddress,
+				describeUserPermission(up),
+			)
+		}
+
+		return up, nil
+	}
+
+	found, mismatch := false, false
+	for _, ca := range up.ContainerAccess {
+		if ca.ContainerId != containerID {
+			continue
+		}
+
+		if ca.Permission == permission {
+			found = true
+		} else {
+			mismatch = true
+		}
+	}
+
+	if !found || mismatch {
+		return nil, fmt.Errorf(
+			"googletagmanager-connector: container permission %s on container %s not in effect for %s after update, observed %s",
+			permission,
+			containerID,
+			up.EmailAddress,
+			describeUserPermission(up),
+		)
+	}
+
+	return up, nil
+}
+
+// verifyUserPermissionDeleted re-fetches the user permission and checks that it no longer exists.
+func verifyUserPermissionDeleted(ctx context.Context, client *tagmanager.Service, pPath string) error {
+	up, err := client.Accounts.UserPermissions.Get(pPath).Context(ctx).Do()
+	if err != nil {
+		if gtm.IsNotFound(err) {
+			return nil
+		}
+
+		return fmt.Errorf("googletagmanager-connector: failed to re-fetch permission for verification: %w", err)
+	}
+
+	return fmt.Errorf(
+		"googletagmanager-connector: permission for %s still exists after delete, observed %s",
+		up.EmailAddress,
+		describeUserPermission(up),
+	)
+}
+
+// hasContainerAccess reports whether the user permission grants any access to the container.
+func hasContainerAccess(up *tagmanager.UserPermission, containerID string) bool {
+	for _, ca := range up.ContainerAccess {
+		if ca.ContainerId == containerID && !isNoAccessPermission(ca.Permission) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeUserPermission renders the account and container access of the user permission for error messages.
+func describeUserPermission(up *tagmanager.UserPermission) string {
+	account := ""
+	if up.AccountAccess != nil {
+		account = up.AccountAccess.Permission
+	}
+
+	containers := make([]string, 0, len(up.ContainerAccess))
+	for _, ca := range up.ContainerAccess {
+		containers = append(containers, fmt.Sprintf("%s:%s", ca.ContainerId, ca.Permission))
+	}
+
+	return fmt.Sprintf("account=%q containers=[%s]", account, strings.Join(containers, ", "))
+}
@@ -0,0 +1,154 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+type workspaceBuilder struct {
+	client       *tagmanager.Service
+	resourceType *v2.ResourceType
+	containers   *containerIndex
+	writer       *mutationWriter
+}
+
+func (w *workspaceBuilder) ResourceType(ctx context.Context) *v2.ResourceType {
+	return workspaceResourceType
+}
+
+func workspaceResource(ctx context.Context, workspace *tagmanager.Workspace, parent *v2.ResourceId) (*v2.Resource, error) {
+	workspaceID := encodeCompositeID(workspace.AccountId, workspace.ContainerId, workspace.WorkspaceId)
+	resource, err := rs.NewResource(
+		workspace.Name,
+		workspaceResourceType,
+		workspaceID,
+		rs.WithParentResourceID(parent),
+		rs.WithDescription(workspace.Description),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return resource, nil
+}
+
+// List returns all the workspaces of the parent container as resource objects.
+func (w *workspaceBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, page, err := parsePageToken(pToken.Token, &v2.ResourceId{ResourceType: workspaceResourceType.Id})
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to parse page token: %w", err)
+	}
+
+	parentPath, err := w.containers.path(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	wlreq := w.client.Accounts.Containers.Workspaces.List(parentPath).Context(ctx)
+
+	if page != "" {
+		wlreq = wlreq.PageToken(page)
+	}
+
+	wl, err := wlreq.Do()
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to list workspaces: %w", err)
+	}
+
+	var rv []*v2.Resource
+	for _, workspace := range wl.Workspace {
+		wr, err := workspaceResource(ctx, workspace, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, wr)
+	}
+
+	nextPage, err := bag.NextToken(wl.NextPageToken)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("googletagmanager-connector: failed to set next page token: %w", err)
+	}
+
+	return rv, nextPage, nil, nil
+}
+
+// Entitlements always returns an empty slice for workspaces.
+func (w *workspaceBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for workspaces since they don't have any entitlements.
+func (w *workspaceBuilder) Grants(ctx context.Context, resource *v2.Resource, pToken *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Create creates a workspace in the parent container, named after the display name and described by the description.
+// In dry-run mode nothing is created and no resource is returned.
+func (w *workspaceBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.ParentResourceId == nil || resource.ParentResourceId.ResourceType != containerResourceType.Id {
+		return nil, nil, fmt.Errorf("googletagmanager-connector: workspaces can only be created under a container")
+	}
+
+	parentPath, err := w.containers.path(ctx, resource.ParentResourceId.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workspace := &tagmanager.Workspace{
+		Name:        resource.DisplayName,
+		Description: resource.Description,
+	}
+
+	created, err := w.writer.createWorkspace(ctx, parentPath, workspace)
+	if err != nil {
+		return nil, nil, fmt.Errorf("googletagmanager-connector: failed to create workspace: %w", err)
+	}
+
+	if created == nil {
+		return nil, nil, nil
+	}
+
+	wr, err := workspaceResource(ctx, created, resource.ParentResourceId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return wr, nil, nil
+}
+
+// Delete deletes the workspace.
+func (w *workspaceBuilder) Delete(ctx context.Context, resourceID *v2.ResourceId) (annotations.Annotations, error) {
+	parts, err := decodeCompositeID(resourceID.Resource, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	wPath := fmt.Sprintf("accounts/%s/containers/%s/workspaces/%s", parts[0], parts[1], parts[2])
+	_, err = w.writer.deleteWorkspace(ctx, wPath)
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-connector: failed to delete workspace: %w", err)
+	}
+
+	return nil, nil
+}
+
+func newWorkspaceBuilder(client *tagmanager.Service, containers *containerIndex, writer *mutationWriter) *workspaceBuilder {
+	return &workspaceBuilder{
+		client:       client,
+		resourceType: workspaceResourceType,
+		containers:   containers,
+		writer:       writer,
+	}
+}
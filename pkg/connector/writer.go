@@ -0,0 +1,198 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+// mutationWriter applies user permission, container and workspace mutations to the API and records them in the audit log.
+// In dry-run mode it only logs the change that would have been made.
+type mutationWriter struct {
+	client   *tagmanager.Service
+	dryRun   bool
+	auditLog *AuditLog
+}
+
+// update writes the user permission, it reports whether the change was applied.
+func (w *mutationWriter) update(ctx context.Context, pPath string, before, after *tagmanager.UserPermission) (bool, error) {
+	if w.dryRun {
+		logDryRun(ctx, "update", pPath, before, after)
+		return false, nil
+	}
+
+	return w.apply(ctx, permissionAuditRecord("update", pPath, before, after), func() error {
+		_, err := w.client.Accounts.UserPermissions.Update(pPath, after).Context(ctx).Do()
+		return err
+	})
+}
+
+// delete removes the user permission, it reports whether the change was applied.
+func (w *mutationWriter) delete(ctx context.Context, pPath string, before *tagmanager.UserPermission) (bool, error) {
+	if w.dryRun {
+		logDryRun(ctx, "delete", pPath, before, nil)
+		return false, nil
+	}
+
+	return w.apply(ctx, permissionAuditRecord("delete", pPath, before, nil), func() error {
+		return w.client.Accounts.UserPermissions.Delete(pPath).Context(ctx).Do()
+	})
+}
+
+// createContainer creates the container in the account at parentPath, it returns nil when the change was not applied.
+func (w *mutationWriter) createContainer(ctx context.Context, parentPath string, container *tagmanager.Container) (*tagmanager.Container, error) {
+	r := resourceAuditRecord("create", parentPath, containerResourceType.Id, container.Name)
+	if w.dryRun {
+		logResourceDryRun(ctx, r)
+		return nil, nil
+	}
+
+	var created *tagmanager.Container
+	_, err := w.apply(ctx, r, func() error {
+		var err error
+		created, err = w.client.Accounts.Containers.Create(parentPath, container).Context(ctx).Do()
+		return err
+	})
+
+	return created, err
+}
+
+// deleteContainer deletes the container, it reports whether the change was applied.
+func (w *mutationWriter) deleteContainer(ctx context.Context, cPath string) (bool, error) {
+	r := resourceAuditRecord("delete", cPath, containerResourceType.Id, "")
+	if w.dryRun {
+		logResourceDryRun(ctx, r)
+		return false, nil
+	}
+
+	return w.apply(ctx, r, func() error {
+		return w.client.Accounts.Containers.Delete(cPath).Context(ctx).Do()
+	})
+}
+
+// createWorkspace creates the workspace in the container at parentPath, it returns nil when the change was not applied.
+func (w *mutationWriter) createWorkspace(ctx context.Context, parentPath string, workspace *tagmanager.Workspace) (*tagmanager.Workspace, error) {
+	r := resourceAuditRecord("create", parentPath, workspaceResourceType.Id, workspace.Name)
+	if w.dryRun {
+		logResourceDryRun(ctx, r)
+		return nil, nil
+	}
+
+	var created *tagmanager.Workspace
+	_, err := w.apply(ctx, r, func() error {
+		var err error
+		created, err = w.client.Accounts.Containers.Workspaces.Create(parentPath, workspace).Context(ctx).Do()
+		return err
+	})
+
+	return created, err
+}
+
+// deleteWorkspace deletes the workspace, it reports whether the change was applied.
+func (w *mutationWriter) deleteWorkspace(ctx context.Context, wPath string) (bool, error) {
+	r := resourceAuditRecord("delete", wPath, workspaceResourceType.Id, "")
+	if w.dryRun {
+		logResourceDryRun(ctx, r)
+		return false, nil
+	}
+
+	return w.apply(ctx, r, func() error {
+		return w.client.Accounts.Containers.Workspaces.Delete(wPath).Context(ctx).Do()
+	})
+}
+
+// apply runs the mutation and records its outcome as a single audit record, it reports whether the mutation was applied.
+// Failing to record the outcome must not turn an applied change into a failed one, so that failure is only logged.
+func (w *mutationWriter) apply(ctx context.Context, r auditRecord, mutate func() error) (bool, error) {
+	opErr := mutate()
+
+	outcome := auditOutcomeSuccess
+	if opErr != nil {
+		outcome = auditOutcomeFailure
+	}
+
+	err := w.auditLog.record(r, outcome, opErr)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn(
+			"googletagmanager-connector: failed to record a change in the audit log",
+			zap.String("operation", r.Operation),
+			zap.String("path", r.Path),
+			zap.String("outcome", outcome),
+			zap.Error(err),
+		)
+	}
+
+	if opErr != nil {
+		return false, opErr
+	}
+
+	return true, nil
+}
+
+func logDryRun(ctx context.Context, operation, pPath string, before, after *tagmanager.UserPermission) {
+	l := ctxzap.Extract(ctx)
+
+	l.Info(
+		"googletagmanager-connector: dry run, skipping user permission change",
+		zap.String("operation", operation),
+		zap.String("path", pPath),
+		zap.String("email", before.EmailAddress),
+		zap.String("account_access_before", accountAccessOf(before)),
+		zap.String("account_access_after", accountAccessOf(after)),
+		zap.Strings("container_access_before", containerAccessOf(before)),
+		zap.Strings("container_access_after", containerAccessOf(after)),
+	)
+}
+
+func logResourceDryRun(ctx context.Context, r auditRecord) {
+	l := ctxzap.Extract(ctx)
+
+	l.Info(
+		"googletagmanager-connector: dry run, skipping resource change",
+		zap.String("operation", r.Operation),
+		zap.String("path", r.Path),
+		zap.String("resource_type", r.ResourceType),
+		zap.String("resource_name", r.ResourceName),
+	)
+}
+
+// cloneUserPermission returns a copy of the access of the user permission that is safe to keep while the original is modified.
+func cloneUserPermission(up *tagmanager.UserPermission) *tagmanager.UserPermission {
+	clone := *up
+	if up.AccountAccess != nil {
+		accountAccess := *up.AccountAccess
+		clone.AccountAccess = &accountAccess
+	}
+
+	clone.ContainerAccess = make([]*tagmanager.ContainerAccess, 0, len(up.ContainerAccess))
+	for _, ca := range up.ContainerAccess {
+		containerAccess := *ca
+		clone.ContainerAccess = append(clone.ContainerAccess, &containerAccess)
+	}
+
+	return &clone
+}
+
+func accountAccessOf(up *tagmanager.UserPermission) string {
+	if up == nil || up.AccountAccess == nil {
+		return ""
+	}
+
+	return up.AccountAccess.Permission
+}
+
+func containerAccessOf(up *tagmanager.UserPermission) []string {
+	if up == nil {
+		return nil
+	}
+
+	rv := make([]string, 0, len(up.ContainerAccess))
+	for _, ca := range up.ContainerAccess {
+		rv = append(rv, fmt.Sprintf("%s:%s", ca.ContainerId, ca.Permission))
+	}
+
+	return rv
+}
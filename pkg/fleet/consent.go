@@ -0,0 +1,196 @@
+package fleet
+
+import (
+	"sort"
+	"strings"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+const (
+	consentStatusNotSet    = "notSet"
+	consentStatusNotNeeded = "notNeeded"
+
+	// ViolationConsentNotSet is reported for tags without a consent setting.
+	ViolationConsentNotSet = "consent_not_set"
+	// ViolationMissingConsentTypes is reported for tags requiring consent without checking all the required consent types.
+	ViolationMissingConsentTypes = "missing_consent_types"
+)
+
+// ConsentViolation is a tag of a live version without the required consent checks.
+type ConsentViolation struct {
+	TagID         string   `json:"tag_id"`
+	TagName       string   `json:"tag_name"`
+	TagType       string   `json:"tag_type"`
+	ConsentStatus string   `json:"consent_status"`
+	ConsentTypes  []string `json:"consent_types"`
+	MissingTypes  []string `json:"missing_types"`
+	Reason        string   `json:"reason"`
+	TagManagerURL string   `json:"tag_manager_url"`
+}
+
+// ConsentContainer lists the consent violations of the live version of a container.
+type ConsentContainer struct {
+	AccountID     string             `json:"account_id"`
+	ContainerID   string             `json:"container_id"`
+	ContainerName string             `json:"container_name"`
+	AuditedTags   int                `json:"audited_tags"`
+	Violations    []ConsentViolation `json:"violations"`
+}
+
+// ConsentReport is the consent settings audit of the live versions.
+type ConsentReport struct {
+	RequiredTypes []string           `json:"required_types"`
+	TagTypes      []string           `json:"tag_types"`
+	Violations    int                `json:"violations"`
+	Containers    []ConsentContainer `json:"containers"`
+}
+
+// NewConsentReport audits the consent settings of the tags of the container versions.
+// Tags that explicitly don't need consent are compliant, tags needing consent have to check every required consent type.
+// When tag types are given only tags of these types are audited.
+func NewConsentReport(versions []*tagmanager.ContainerVersion, requiredTypes []string, tagTypes []string) *ConsentReport {
+	rv := &ConsentReport{
+		RequiredTypes: normalizeList(requiredTypes),
+		TagTypes:      normalizeList(tagTypes),
+	}
+
+	audited := make(map[string]struct{}, len(rv.TagTypes))
+	for _, t := range rv.TagTypes {
+		audited[t] = struct{}{}
+	}
+
+	for _, cv := range versions {
+		cc := ConsentContainer{
+			AccountID:   cv.AccountId,
+			ContainerID: cv.ContainerId,
+		}
+		if cv.Container != nil {
+			cc.ContainerName = cv.Container.Name
+		}
+
+		for _, tag := range cv.Tag {
+			if _, ok := audited[strings.ToLower(tag.Type)]; !ok && len(audited) > 0 {
+				continue
+			}
+
+			cc.AuditedTags++
+
+			violation, ok := rv.check(tag)
+			if !ok {
+				cc.Violations = append(cc.Violations, violation)
+			}
+		}
+
+		rv.Violations += len(cc.Violations)
+		rv.Containers = append(rv.Containers, cc)
+	}
+
+	return rv
+}
+
+// check returns the violation of the tag and false if its consent settings are not compliant.
+func (r *ConsentReport) check(tag *tagmanager.Tag) (ConsentViolation, bool) {
+	violation := ConsentViolation{
+		TagID:         tag.TagId,
+		TagName:       tag.Name,
+		TagType:       tag.Type,
+		ConsentStatus: consentStatusNotSet,
+		TagManagerURL: tag.TagManagerUrl,
+	}
+
+	if tag.ConsentSettings != nil && tag.ConsentSettings.ConsentStatus != "" {
+		violation.ConsentStatus = tag.ConsentSettings.ConsentStatus
+		violation.ConsentTypes = consentTypes(tag.ConsentSettings.ConsentType)
+	}
+
+	switch violation.ConsentStatus {
+	case consentStatusNotSet:
+		violation.Reason = ViolationConsentNotSet
+		violation.MissingTypes = r.RequiredTypes
+		return violation, false
+	case consentStatusNotNeeded:
+		return violation, true
+	}
+
+	checked := make(map[string]struct{}, len(violation.ConsentTypes))
+	for _, t := range violation.ConsentTypes {
+		checked[t] = struct{}{}
+	}
+
+	for _, t := range r.RequiredTypes {
+		if _, ok := checked[t]; !ok {
+			violation.MissingTypes = append(violation.MissingTypes, t)
+		}
+	}
+
+	if len(violation.MissingTypes) > 0 {
+		violation.Reason = ViolationMissingConsentTypes
+		return violation, false
+	}
+
+	return violation, true
+}
+
+// ExceedsViolations reports whether the report has more violations than allowed.
+func (r *ConsentReport) ExceedsViolations(maxViolations int) bool {
+	return r.Violations > maxViolations
+}
+
+// Records returns one record per violation.
+func (r *ConsentReport) Records() [][]string {
+	rv := [][]string{{
+		"account_id", "container_id", "container_name", "tag_id", "tag_name", "tag_type",
+		"consent_status", "consent_types", "missing_types", "reason", "tag_manager_url",
+	}}
+
+	for _, c := range r.Containers {
+		for _, v := range c.Violations {
+			rv = append(rv, []string{
+				c.AccountID, c.ContainerID, c.ContainerName, v.TagID, v.TagName, v.TagType,
+				v.ConsentStatus, strings.Join(v.ConsentTypes, " "), strings.Join(v.MissingTypes, " "), v.Reason, v.TagManagerURL,
+			})
+		}
+	}
+
+	return rv
+}
+
+// consentTypes returns the consent types of the list parameter of the consent setting.
+func consentTypes(p *tagmanager.Parameter) []string {
+	if p == nil {
+		return nil
+	}
+
+	var rv []string
+	for _, item := range p.List {
+		if item.Value != "" {
+			rv = append(rv, strings.ToLower(item.Value))
+		}
+	}
+
+	return rv
+}
+
+// normalizeList lowercases, trims, deduplicates and sorts the values.
+func normalizeList(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	var rv []string
+	for _, v := range values {
+		v = strings.ToLower(strings.TrimSpace(v))
+		if v == "" {
+			continue
+		}
+
+		if _, ok := seen[v]; ok {
+			continue
+		}
+
+		seen[v] = struct{}{}
+		rv = append(rv, v)
+	}
+
+	sort.Strings(rv)
+
+	return rv
+}
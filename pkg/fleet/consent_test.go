@@ -0,0 +1,167 @@
+package fleet
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+func consentTag(id, tagType, status string, types ...string) *tagmanager.Tag {
+	tag := &tagmanager.Tag{TagId: id, Name: "tag " + id, Type: tagType}
+	if status == "" {
+		return tag
+	}
+
+	tag.ConsentSettings = &tagmanager.TagConsentSetting{ConsentStatus: status}
+	if len(types) > 0 {
+		p := &tagmanager.Parameter{Type: "list"}
+		for _, t := range types {
+			p.List = append(p.List, &tagmanager.Parameter{Type: "template", Value: t})
+		}
+		tag.ConsentSettings.ConsentType = p
+	}
+
+	return tag
+}
+
+func TestConsentReportCheck(t *testing.T) {
+	r := &ConsentReport{RequiredTypes: []string{"ad_storage", "analytics_storage"}}
+
+	tests := []struct {
+		name        string
+		tag         *tagmanager.Tag
+		wantOK      bool
+		wantReason  string
+		wantMissing []string
+	}{
+		{
+			name:        "no consent settings",
+			tag:         consentTag("1", "html", ""),
+			wantReason:  ViolationConsentNotSet,
+			wantMissing: []string{"ad_storage", "analytics_storage"},
+		},
+		{
+			name:        "consent explicitly not set",
+			tag:         consentTag("2", "html", consentStatusNotSet),
+			wantReason:  ViolationConsentNotSet,
+			wantMissing: []string{"ad_storage", "analytics_storage"},
+		},
+		{
+			name:   "consent not needed",
+			tag:    consentTag("3", "html", consentStatusNotNeeded),
+			wantOK: true,
+		},
+		{
+			name:        "missing consent types",
+			tag:         consentTag("4", "html", "needed", "AD_STORAGE"),
+			wantReason:  ViolationMissingConsentTypes,
+			wantMissing: []string{"analytics_storage"},
+		},
+		{
+			name:        "needed without consent types",
+			tag:         consentTag("5", "html", "needed"),
+			wantReason:  ViolationMissingConsentTypes,
+			wantMissing: []string{"ad_storage", "analytics_storage"},
+		},
+		{
+			name:   "all consent types checked",
+			tag:    consentTag("6", "html", "needed", "analytics_storage", "ad_storage", "functionality_storage"),
+			wantOK: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			violation, ok := r.check(tt.tag)
+			if ok != tt.wantOK {
+				t.Fatalf("check() ok = %v, want %v", ok, tt.wantOK)
+			}
+
+			if violation.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", violation.Reason, tt.wantReason)
+			}
+
+			if !reflect.DeepEqual(violation.MissingTypes, tt.wantMissing) {
+				t.Errorf("missing types = %v, want %v", violation.MissingTypes, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestNewConsentReport(t *testing.T) {
+	versions := []*tagmanager.ContainerVersion{
+		{
+			AccountId:   "1",
+			ContainerId: "10",
+			Container:   &tagmanager.Container{Name: "web"},
+			Tag: []*tagmanager.Tag{
+				consentTag("1", "html", ""),
+				consentTag("2", "gaawe", consentStatusNotNeeded),
+				consentTag("3", "gaawe", "needed", "ad_storage"),
+			},
+		},
+		{
+			AccountId:   "1",
+			ContainerId: "11",
+			Tag:         []*tagmanager.Tag{consentTag("4", "HTML", "needed", "ad_storage", "analytics_storage")},
+		},
+	}
+
+	tests := []struct {
+		name           string
+		tagTypes       []string
+		wantAudited    []int
+		wantViolations int
+	}{
+		{"every tag type", nil, []int{3, 1}, 2},
+		{"only html tags", []string{" HTML "}, []int{1, 1}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewConsentReport(versions, []string{"Analytics_Storage", "ad_storage", "ad_storage"}, tt.tagTypes)
+
+			if want := []string{"ad_storage", "analytics_storage"}; !reflect.DeepEqual(r.RequiredTypes, want) {
+				t.Errorf("RequiredTypes = %v, want %v", r.RequiredTypes, want)
+			}
+
+			var audited []int
+			for _, c := range r.Containers {
+				audited = append(audited, c.AuditedTags)
+			}
+
+			if !reflect.DeepEqual(audited, tt.wantAudited) {
+				t.Errorf("audited tags = %v, want %v", audited, tt.wantAudited)
+			}
+
+			if r.Violations != tt.wantViolations {
+				t.Errorf("Violations = %d, want %d", r.Violations, tt.wantViolations)
+			}
+		})
+	}
+}
+
+func TestConsentReportExceedsViolations(t *testing.T) {
+	tests := []struct {
+		name          string
+		violations    int
+		maxViolations int
+		want          bool
+	}{
+		{"no violations", 0, 0, false},
+		{"below the threshold", 2, 3, false},
+		{"at the threshold", 3, 3, false},
+		{"one above the threshold", 4, 3, true},
+		{"any violation with none allowed", 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ConsentReport{Violations: tt.violations}
+			if got := r.ExceedsViolations(tt.maxViolations); got != tt.want {
+				t.Errorf("ExceedsViolations(%d) = %v, want %v", tt.maxViolations, got, tt.want)
+			}
+		})
+	}
+}
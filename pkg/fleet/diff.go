@@ -0,0 +1,338 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// volatileFields change between versions without the entity being edited, they are left out of diffs.
+var volatileFields = map[string]struct{}{
+	"accountId":     {},
+	"containerId":   {},
+	"workspaceId":   {},
+	"fingerprint":   {},
+	"path":          {},
+	"tagManagerUrl": {},
+}
+
+// FieldChange is the change of a single field of an entity, parameters are addressed by their key.
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to,omitempty"`
+}
+
+// EntityChange is an entity that was added, removed or modified between two versions.
+type EntityChange struct {
+	Kind   string        `json:"kind"`
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Change string        `json:"change"`
+	Fields []FieldChange `json:"fields,omitempty"`
+}
+
+// VersionDiff is the difference between two versions of a container.
+type VersionDiff struct {
+	AccountID     string         `json:"account_id"`
+	ContainerID   string         `json:"container_id"`
+	FromVersionID string         `json:"from_version_id"`
+	ToVersionID   string         `json:"to_version_id"`
+	Changes       []EntityChange `json:"changes"`
+}
+
+// DiffVersions returns the tags, triggers, variables, templates, clients and zones that differ between the versions, keyed by their id.
+func DiffVersions(from, to *tagmanager.ContainerVersion) (*VersionDiff, error) {
+	rv := &VersionDiff{
+		AccountID:     to.AccountId,
+		ContainerID:   to.ContainerId,
+		FromVersionID: from.ContainerVersionId,
+		ToVersionID:   to.ContainerVersionId,
+	}
+
+	fromEntities, err := versionEntities(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toEntities, err := versionEntities(to)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kind := range entityKinds {
+		rv.Changes = append(rv.Changes, diffEntities(kind, fromEntities[kind], toEntities[kind])...)
+	}
+
+	return rv, nil
+}
+
+// WriteText writes the diff in a human readable form.
+func (d *VersionDiff) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "container %s/%s: version %s -> %s\n", d.AccountID, d.ContainerID, d.FromVersionID, d.ToVersionID)
+
+	if len(d.Changes) == 0 {
+		b.WriteString("no changes\n")
+	}
+
+	for _, c := range d.Changes {
+		fmt.Fprintf(&b, "%s %s %s %q\n", c.Change, c.Kind, c.ID, c.Name)
+		for _, f := range c.Fields {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", f.Field, textValue(f.From), textValue(f.To))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// entity is a version entity flattened to its fields.
+type entity struct {
+	id     string
+	name   string
+	fields map[string]any
+}
+
+// entityKinds are the kinds of entities compared between versions, in the order their changes are reported.
+var entityKinds = []string{"tag", "trigger", "variable", "template", "client", "zone"}
+
+// entityIDFields are the fields holding the id of each kind of entity.
+var entityIDFields = map[string]string{
+	"tag":      "tagId",
+	"trigger":  "triggerId",
+	"variable": "variableId",
+	"template": "templateId",
+	"client":   "clientId",
+	"zone":     "zoneId",
+}
+
+func versionEntities(cv *tagmanager.ContainerVersion) (map[string]map[string]entity, error) {
+	lists := map[string]any{
+		"tag":      cv.Tag,
+		"trigger":  cv.Trigger,
+		"variable": cv.Variable,
+		"template": cv.CustomTemplate,
+		"client":   cv.Client,
+		"zone":     cv.Zone,
+	}
+
+	rv := make(map[string]map[string]entity, len(lists))
+	for kind, list := range lists {
+		data, err := json.Marshal(list)
+		if err != nil {
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to marshal %s entities: %w", kind, err)
+		}
+
+		var raw []map[string]any
+		err = json.Unmarshal(data, &raw)
+		if err != nil {
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to unmarshal %s entities: %w", kind, err)
+		}
+
+		entities := make(map[string]entity, len(raw))
+		for _, r := range raw {
+			id, _ := r[entityIDFields[kind]].(string)
+			name, _ := r["name"].(string)
+
+			fields := make(map[string]any)
+			for k, v := range r {
+				if _, ok := volatileFields[k]; ok {
+					continue
+				}
+
+				flatten(k, v, fields)
+			}
+
+			entities[id] = entity{id: id, name: name, fields: fields}
+		}
+
+		rv[kind] = entities
+	}
+
+	return rv, nil
+}
+
+// flatten adds the leaf values of v to fields under dotted paths, list items that have a key are addressed by their key rather than their index.
+func flatten(prefix string, v any, fields map[string]any) {
+	switch value := v.(type) {
+	case map[string]any:
+		for k, item := range value {
+			flatten(prefix+"."+k, item, fields)
+		}
+	case []any:
+		for i, item := range value {
+			segment := strconv.Itoa(i)
+			if m, ok := item.(map[string]any); ok {
+				if key, ok := m["key"].(string); ok && key != "" {
+					segment = key
+				}
+			}
+
+			flatten(prefix+"["+segment+"]", item, fields)
+		}
+	default:
+		fields[prefix] = value
+	}
+}
+
+func diffEntities(kind string, from, to map[string]entity) []EntityChange {
+	ids := make(map[string]struct{}, len(from)+len(to))
+	for id := range from {
+		ids[id] = struct{}{}
+	}
+	for id := range to {
+		ids[id] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sortIDs(sorted)
+
+	var rv []EntityChange
+	for _, id := range sorted {
+		f, inFrom := from[id]
+		t, inTo := to[id]
+
+		switch {
+		case !inFrom:
+			rv = append(rv, EntityChange{Kind: kind, ID: id, Name: t.name, Change: ChangeAdded})
+		case !inTo:
+			rv = append(rv, EntityChange{Kind: kind, ID: id, Name: f.name, Change: ChangeRemoved})
+		default:
+			fields := diffFields(f.fields, t.fields)
+			if len(fields) > 0 {
+				rv = append(rv, EntityChange{Kind: kind, ID: id, Name: t.name, Change: ChangeModified, Fields: fields})
+			}
+		}
+	}
+
+	return rv
+}
+
+func diffFields(from, to map[string]any) []FieldChange {
+	keys := make(map[string]struct{}, len(from)+len(to))
+	for k := range from {
+		keys[k] = struct{}{}
+	}
+	for k := range to {
+		keys[k] = struct{}{}
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	var rv []FieldChange
+	for _, k := range sorted {
+		f, inFrom := from[k]
+		t, inTo := to[k]
+		if inFrom && inTo && f == t {
+			continue
+		}
+
+		rv = append(rv, FieldChange{Field: k, From: f, To: t})
+	}
+
+	return rv
+}
+
+// sortIDs sorts the entity ids numerically when they are numbers.
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA == nil && errB == nil {
+			return a < b
+		}
+
+		return ids[i] < ids[j]
+	})
+}
+
+// textValue formats a field value for the text diff, shortening long values such as template code.
+func textValue(v any) string {
+	if v == nil {
+		return "<unset>"
+	}
+
+	s := fmt.Sprintf("%v", v)
+	if str, ok := v.(string); ok {
+		s = strconv.Quote(str)
+	}
+
+	// truncate by rune so multi-byte characters are not split
+	const maxLength = 120
+	if runes := []rune(s); len(runes) > maxLength {
+		s = string(runes[:maxLength]) + "..."
+	}
+
+	return s
+}
+
+// generatedFields are set by Tag Manager when an entity is created, they are ignored when comparing an entity to its import.
+var generatedFields = map[string]struct{}{
+	"folderId":         {},
+	"templateId":       {},
+	"variableId":       {},
+	"triggerId":        {},
+	"tagId":            {},
+	"galleryReference": {},
+}
+
+// sameEntity reports whether the entities have the same fields, ignoring the ones generated by Tag Manager.
+func sameEntity(a, b any) bool {
+	fa, err := entityFields(a)
+	if err != nil {
+		return false
+	}
+
+	fb, err := entityFields(b)
+	if err != nil {
+		return false
+	}
+
+	return len(diffFields(fa, fb)) == 0
+}
+
+func entityFields(e any) (map[string]any, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]any
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]any)
+	for k, v := range raw {
+		if _, ok := volatileFields[k]; ok {
+			continue
+		}
+		if _, ok := generatedFields[k]; ok {
+			continue
+		}
+
+		flatten(k, v, fields)
+	}
+
+	return fields, nil
+}
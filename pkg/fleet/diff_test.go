@@ -0,0 +1,160 @@
+package fleet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  map[string]any
+	}{
+		{"scalar", "x", map[string]any{"v": "x"}},
+		{
+			"nested map",
+			map[string]any{"a": map[string]any{"b": 1.0}, "c": true},
+			map[string]any{"v.a.b": 1.0, "v.c": true},
+		},
+		{
+			"list items with a key",
+			[]any{
+				map[string]any{"key": "html", "value": "<p>"},
+				map[string]any{"key": "supportDocumentWrite", "value": "false"},
+			},
+			map[string]any{
+				"v[html].key":                   "html",
+				"v[html].value":                 "<p>",
+				"v[supportDocumentWrite].key":   "supportDocumentWrite",
+				"v[supportDocumentWrite].value": "false",
+			},
+		},
+		{
+			"list items without a key",
+			[]any{"a", map[string]any{"key": "", "value": "b"}},
+			map[string]any{"v[0]": "a", "v[1].key": "", "v[1].value": "b"},
+		},
+		{"empty list", []any{}, map[string]any{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]any)
+			flatten("v", tt.value, got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("flatten() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffFields(t *testing.T) {
+	tests := []struct {
+		name string
+		from map[string]any
+		to   map[string]any
+		want []FieldChange
+	}{
+		{"equal", map[string]any{"a": "1"}, map[string]any{"a": "1"}, nil},
+		{
+			"sorted changes",
+			map[string]any{"b": "1", "a": "x", "c": true},
+			map[string]any{"b": "2", "a": "x", "d": 1.0},
+			[]FieldChange{
+				{Field: "b", From: "1", To: "2"},
+				{Field: "c", From: true},
+				{Field: "d", To: 1.0},
+			},
+		},
+		{"type change", map[string]any{"a": "1"}, map[string]any{"a": 1.0}, []FieldChange{{Field: "a", From: "1", To: 1.0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffFields(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffFields() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffVersions(t *testing.T) {
+	from := &tagmanager.ContainerVersion{
+		AccountId:          "1",
+		ContainerId:        "2",
+		ContainerVersionId: "3",
+		Tag: []*tagmanager.Tag{
+			{TagId: "10", Name: "Kept", Type: "html", Fingerprint: "a", Parameter: []*tagmanager.Parameter{{Key: "html", Type: "template", Value: "<p>"}}},
+			{TagId: "9", Name: "Edited", Type: "html", Parameter: []*tagmanager.Parameter{{Key: "html", Type: "template", Value: "<b>"}}},
+			{TagId: "11", Name: "Removed", Type: "img"},
+		},
+		Variable: []*tagmanager.Variable{{VariableId: "5", Name: "Host", Type: "c"}},
+	}
+
+	to := &tagmanager.ContainerVersion{
+		AccountId:          "1",
+		ContainerId:        "2",
+		ContainerVersionId: "4",
+		Tag: []*tagmanager.Tag{
+			{TagId: "10", Name: "Kept", Type: "html", Fingerprint: "b", Parameter: []*tagmanager.Parameter{{Key: "html", Type: "template", Value: "<p>"}}},
+			{TagId: "9", Name: "Edited", Type: "html", Parameter: []*tagmanager.Parameter{{Key: "html", Type: "template", Value: "<i>"}}},
+			{TagId: "12", Name: "Added", Type: "img"},
+		},
+		Trigger:  []*tagmanager.Trigger{{TriggerId: "7", Name: "All Pages", Type: "pageview"}},
+		Variable: []*tagmanager.Variable{{VariableId: "5", Name: "Host", Type: "c"}},
+	}
+
+	d, err := DiffVersions(from, to)
+	if err != nil {
+		t.Fatalf("DiffVersions() returned error: %v", err)
+	}
+
+	if d.FromVersionID != "3" || d.ToVersionID != "4" {
+		t.Errorf("versions = %s -> %s, want 3 -> 4", d.FromVersionID, d.ToVersionID)
+	}
+
+	want := []EntityChange{
+		{Kind: "tag", ID: "9", Name: "Edited", Change: ChangeModified, Fields: []FieldChange{{Field: "parameter[html].value", From: "<b>", To: "<i>"}}},
+		{Kind: "tag", ID: "11", Name: "Removed", Change: ChangeRemoved},
+		{Kind: "tag", ID: "12", Name: "Added", Change: ChangeAdded},
+		{Kind: "trigger", ID: "7", Name: "All Pages", Change: ChangeAdded},
+	}
+
+	if !reflect.DeepEqual(d.Changes, want) {
+		t.Errorf("DiffVersions() changes = %+v, want %+v", d.Changes, want)
+	}
+}
+
+func TestTextValue(t *testing.T) {
+	long := strings.Repeat("é", 200)
+
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"unset", nil, "<unset>"},
+		{"string is quoted", "a\nb", `"a\nb"`},
+		{"number", 1.5, "1.5"},
+		// the opening quote counts toward the limit
+		{"long string is cut by rune", long, `"` + strings.Repeat("é", 119) + "..."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := textValue(tt.value)
+			if !utf8.ValidString(got) {
+				t.Fatalf("textValue() = %q, is not valid UTF-8", got)
+			}
+
+			if got != tt.want {
+				t.Errorf("textValue() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
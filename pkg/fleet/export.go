@@ -0,0 +1,271 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"google.golang.org/api/tagmanager/v2"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
+)
+
+const (
+	// exportFormatVersion is the version of the container export format of the Tag Manager UI.
+	exportFormatVersion = 2
+	exportTimeLayout    = "2006-01-02 15:04:05"
+
+	manifestFileName = "manifest.json"
+
+	ExportKindLiveVersion = "live_version"
+	ExportKindWorkspace   = "workspace"
+)
+
+// ExportFile is a container version in the format of the Tag Manager UI container export, which the UI can import.
+type ExportFile struct {
+	ExportFormatVersion int                          `json:"exportFormatVersion"`
+	ExportTime          string                       `json:"exportTime"`
+	ContainerVersion    *tagmanager.ContainerVersion `json:"containerVersion"`
+}
+
+// ReadExportFile reads a container export of the Tag Manager UI or of the export command.
+func ReadExportFile(path string) (*ExportFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to read export file: %w", err)
+	}
+
+	ef := &ExportFile{}
+	err = json.Unmarshal(data, ef)
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to parse export file: %w", err)
+	}
+
+	if ef.ContainerVersion == nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: export file %s has no container version", path)
+	}
+
+	return ef, nil
+}
+
+// ManifestEntry is a file written by the export.
+type ManifestEntry struct {
+	AccountID     string `json:"account_id"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	PublicID      string `json:"public_id"`
+	Kind          string `json:"kind"`
+	VersionID     string `json:"version_id,omitempty"`
+	WorkspaceID   string `json:"workspace_id,omitempty"`
+	Fingerprint   string `json:"fingerprint"`
+	File          string `json:"file"`
+}
+
+// Manifest lists the files written by the export with the fingerprints of the exported versions and workspaces.
+type Manifest struct {
+	ExportTime string          `json:"export_time"`
+	Entries    []ManifestEntry `json:"entries"`
+}
+
+// Export writes the live version of every container of the accounts, and optionally the current state of their workspaces,
+// to dir as account/container/version.json and account/container/workspaces/workspace.json, along with a manifest.
+func Export(ctx context.Context, client *tagmanager.Service, accounts []string, dir string, includeWorkspaces bool) (*Manifest, error) {
+	containers, err := Containers(ctx, client, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	manifest := &Manifest{ExportTime: now.Format(time.RFC3339)}
+	for _, container := range containers {
+		entry := ManifestEntry{
+			AccountID:     container.AccountId,
+			ContainerID:   container.ContainerId,
+			ContainerName: container.Name,
+			PublicID:      container.PublicId,
+		}
+
+		cv, err := client.Accounts.Containers.Versions.Live(container.Path).Context(ctx).Do()
+		if err != nil && !gtm.IsNotFound(err) {
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to get live version of container %s: %w", container.ContainerId, err)
+		}
+
+		// containers that were never published have no live version
+		if err == nil {
+			e := entry
+			e.Kind = ExportKindLiveVersion
+			e.VersionID = cv.ContainerVersionId
+			e.Fingerprint = cv.Fingerprint
+			e.File = filepath.Join(container.AccountId, container.ContainerId, "version.json")
+
+			err = writeExportFile(filepath.Join(dir, e.File), cv, now)
+			if err != nil {
+				return nil, err
+			}
+
+			manifest.Entries = append(manifest.Entries, e)
+		}
+
+		if !includeWorkspaces {
+			continue
+		}
+
+		err = client.Accounts.Containers.Workspaces.List(container.Path).Pages(ctx, func(wl *tagmanager.ListWorkspacesResponse) error {
+			for _, workspace := range wl.Workspace {
+				wv, err := WorkspaceVersion(ctx, client, container, workspace)
+				if err != nil {
+					return err
+				}
+
+				e := entry
+				e.Kind = ExportKindWorkspace
+				e.WorkspaceID = workspace.WorkspaceId
+				e.Fingerprint = workspace.Fingerprint
+				e.File = filepath.Join(container.AccountId, container.ContainerId, "workspaces", workspace.WorkspaceId+".json")
+
+				err = writeExportFile(filepath.Join(dir, e.File), wv, now)
+				if err != nil {
+					return err
+				}
+
+				manifest.Entries = append(manifest.Entries, e)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to export workspaces of container %s: %w", container.ContainerId, err)
+		}
+	}
+
+	err = writeJSONFile(filepath.Join(dir, manifestFileName), manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// WorkspaceVersion returns the current state of the workspace assembled as a container version, the way the Tag Manager UI exports workspaces.
+func WorkspaceVersion(ctx context.Context, client *tagmanager.Service, container *tagmanager.Container, workspace *tagmanager.Workspace) (*tagmanager.ContainerVersion, error) {
+	ws := client.Accounts.Containers.Workspaces
+	wPath := workspace.Path
+
+	cv := &tagmanager.ContainerVersion{
+		AccountId:   workspace.AccountId,
+		ContainerId: workspace.ContainerId,
+		Container:   container,
+		Name:        workspace.Name,
+		Description: workspace.Description,
+		Fingerprint: workspace.Fingerprint,
+	}
+
+	err := ws.BuiltInVariables.List(wPath).Pages(ctx, func(r *tagmanager.ListEnabledBuiltInVariablesResponse) error {
+		cv.BuiltInVariable = append(cv.BuiltInVariable, r.BuiltInVariable...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list built-in variables: %w", err)
+	}
+
+	err = ws.Folders.List(wPath).Pages(ctx, func(r *tagmanager.ListFoldersResponse) error {
+		cv.Folder = append(cv.Folder, r.Folder...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list folders: %w", err)
+	}
+
+	err = ws.Tags.List(wPath).Pages(ctx, func(r *tagmanager.ListTagsResponse) error {
+		cv.Tag = append(cv.Tag, r.Tag...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list tags: %w", err)
+	}
+
+	err = ws.Triggers.List(wPath).Pages(ctx, func(r *tagmanager.ListTriggersResponse) error {
+		cv.Trigger = append(cv.Trigger, r.Trigger...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list triggers: %w", err)
+	}
+
+	err = ws.Variables.List(wPath).Pages(ctx, func(r *tagmanager.ListVariablesResponse) error {
+		cv.Variable = append(cv.Variable, r.Variable...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list variables: %w", err)
+	}
+
+	err = ws.Templates.List(wPath).Pages(ctx, func(r *tagmanager.ListTemplatesResponse) error {
+		cv.CustomTemplate = append(cv.CustomTemplate, r.Template...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list templates: %w", err)
+	}
+
+	if isServerContainer(container) {
+		err = ws.Clients.List(wPath).Pages(ctx, func(r *tagmanager.ListClientsResponse) error {
+			cv.Client = append(cv.Client, r.Client...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to list clients: %w", err)
+		}
+	} else {
+		err = ws.Zones.List(wPath).Pages(ctx, func(r *tagmanager.ListZonesResponse) error {
+			cv.Zone = append(cv.Zone, r.Zone...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to list zones: %w", err)
+		}
+	}
+
+	return cv, nil
+}
+
+// isServerContainer reports whether the container is a server-side container, which has clients instead of zones.
+func isServerContainer(container *tagmanager.Container) bool {
+	for _, uc := range container.UsageContext {
+		if uc == "server" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func writeExportFile(path string, cv *tagmanager.ContainerVersion, exportTime time.Time) error {
+	return writeJSONFile(path, &ExportFile{
+		ExportFormatVersion: exportFormatVersion,
+		ExportTime:          exportTime.Format(exportTimeLayout),
+		ContainerVersion:    cv,
+	})
+}
+
+func writeJSONFile(path string, v any) error {
+	err := os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("googletagmanager-fleet: failed to create export directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("googletagmanager-fleet: failed to marshal %s: %w", filepath.Base(path), err)
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("googletagmanager-fleet: failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
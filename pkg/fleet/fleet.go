@@ -0,0 +1,174 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"google.golang.org/api/tagmanager/v2"
+
+	"github.com/conductorone/baton-googletagmanager/pkg/gtm"
+)
+
+// Containers returns the containers of the accounts, or of every account the client can access when no accounts are given.
+func Containers(ctx context.Context, client *tagmanager.Service, accounts []string) ([]*tagmanager.Container, error) {
+	var rv []*tagmanager.Container
+	err := gtm.WalkContainers(ctx, client, accounts, func(container *tagmanager.Container) bool {
+		rv = append(rv, container)
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: %w", err)
+	}
+
+	return rv, nil
+}
+
+// FindContainer returns the container with the id among the containers of the accounts.
+func FindContainer(ctx context.Context, client *tagmanager.Service, accounts []string, containerID string) (*tagmanager.Container, error) {
+	containers, err := Containers(ctx, client, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, container := range containers {
+		if container.ContainerId == containerID || container.PublicId == containerID {
+			return container, nil
+		}
+	}
+
+	return nil, fmt.Errorf("googletagmanager-fleet: container %s not found", containerID)
+}
+
+// defaultWorkspaceName is the name of the workspace every container is created with.
+const defaultWorkspaceName = "Default Workspace"
+
+// FindWorkspace returns the workspace of the container with the id.
+// Without an id it returns the default workspace, or the oldest workspace if it was renamed.
+func FindWorkspace(ctx context.Context, client *tagmanager.Service, container *tagmanager.Container, workspaceID string) (*tagmanager.Workspace, error) {
+	var rv *tagmanager.Workspace
+	err := client.Accounts.Containers.Workspaces.List(container.Path).Pages(ctx, func(wl *tagmanager.ListWorkspacesResponse) error {
+		for _, workspace := range wl.Workspace {
+			switch {
+			case workspaceID != "":
+				if workspace.WorkspaceId == workspaceID {
+					rv = workspace
+				}
+			case workspace.Name == defaultWorkspaceName:
+				rv = workspace
+			case rv == nil || (rv.Name != defaultWorkspaceName && workspaceIDLess(workspace.WorkspaceId, rv.WorkspaceId)):
+				rv = workspace
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to list workspaces of container %s: %w", container.ContainerId, err)
+	}
+
+	if rv == nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: workspace %s not found in container %s", workspaceID, container.ContainerId)
+	}
+
+	return rv, nil
+}
+
+func workspaceIDLess(a, b string) bool {
+	ai, errA := strconv.ParseInt(a, 10, 64)
+	bi, errB := strconv.ParseInt(b, 10, 64)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+
+	return ai < bi
+}
+
+// LiveVersionID returns the id of the published version of the container.
+func LiveVersionID(ctx context.Context, client *tagmanager.Service, container *tagmanager.Container) (string, error) {
+	cv, err := client.Accounts.Containers.Versions.Live(container.Path).Fields("containerVersionId").Context(ctx).Do()
+	if err != nil {
+		return "", fmt.Errorf("googletagmanager-fleet: failed to get live version of container %s: %w", container.ContainerId, err)
+	}
+
+	return cv.ContainerVersionId, nil
+}
+
+// PreviousVersionID returns the id of the latest non deleted version of the container created before the version.
+func PreviousVersionID(ctx context.Context, client *tagmanager.Service, container *tagmanager.Container, versionID string) (string, error) {
+	current, err := strconv.ParseInt(versionID, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("googletagmanager-fleet: invalid version id %s: %w", versionID, err)
+	}
+
+	var previous int64 = -1
+	pageToken := ""
+	for {
+		vhreq := client.Accounts.Containers.VersionHeaders.List(container.Path).Context(ctx)
+
+		if pageToken != "" {
+			vhreq = vhreq.PageToken(pageToken)
+		}
+
+		vh, err := vhreq.Do()
+		if err != nil {
+			return "", fmt.Errorf("googletagmanager-fleet: failed to list version headers: %w", err)
+		}
+
+		for _, header := range vh.ContainerVersionHeader {
+			id, err := strconv.ParseInt(header.ContainerVersionId, 10, 64)
+			if err != nil || header.Deleted {
+				continue
+			}
+
+			if id < current && id > previous {
+				previous = id
+			}
+		}
+
+		if vh.NextPageToken == "" {
+			break
+		}
+		pageToken = vh.NextPageToken
+	}
+
+	if previous < 0 {
+		return "", fmt.Errorf("googletagmanager-fleet: container %s has no version before %s", container.ContainerId, versionID)
+	}
+
+	return strconv.FormatInt(previous, 10), nil
+}
+
+// GetVersion returns the version of the container.
+func GetVersion(ctx context.Context, client *tagmanager.Service, container *tagmanager.Container, versionID string) (*tagmanager.ContainerVersion, error) {
+	cv, err := client.Accounts.Containers.Versions.Get(container.Path + "/versions/" + versionID).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to get version %s of container %s: %w", versionID, container.ContainerId, err)
+	}
+
+	return cv, nil
+}
+
+// LiveVersions returns the live version of every container of the accounts, skipping containers that were never published.
+func LiveVersions(ctx context.Context, client *tagmanager.Service, accounts []string) ([]*tagmanager.ContainerVersion, error) {
+	containers, err := Containers(ctx, client, accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	var rv []*tagmanager.ContainerVersion
+	for _, container := range containers {
+		cv, err := client.Accounts.Containers.Versions.Live(container.Path).Context(ctx).Do()
+		if err != nil {
+			if gtm.IsNotFound(err) {
+				continue
+			}
+
+			return nil, fmt.Errorf("googletagmanager-fleet: failed to get live version of container %s: %w", container.ContainerId, err)
+		}
+
+		rv = append(rv, cv)
+	}
+
+	return rv, nil
+}
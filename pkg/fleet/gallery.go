@@ -0,0 +1,146 @@
+package fleet
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+// GalleryTemplate is a Community Template Gallery template installed in the live version of a container.
+type GalleryTemplate struct {
+	AccountID     string `json:"account_id"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	TemplateID    string `json:"template_id"`
+	Name          string `json:"name"`
+	Version       string `json:"version"`
+	Signature     string `json:"signature"`
+	IsModified    bool   `json:"is_modified"`
+	// SignatureDrift is set when the template is pinned to a signature other than the most common one in the fleet.
+	SignatureDrift bool   `json:"signature_drift"`
+	TagManagerURL  string `json:"tag_manager_url"`
+}
+
+// Stale reports whether the template was modified locally or drifted from the signature used by the rest of the fleet.
+func (t GalleryTemplate) Stale() bool {
+	return t.IsModified || t.SignatureDrift
+}
+
+// GalleryRepository groups the installations of the templates of a gallery repository.
+type GalleryRepository struct {
+	Host       string `json:"host"`
+	Owner      string `json:"owner"`
+	Repository string `json:"repository"`
+	// Signature is the signature most templates of the repository are pinned to.
+	Signature  string            `json:"signature"`
+	Signatures []string          `json:"signatures"`
+	Stale      int               `json:"stale"`
+	Templates  []GalleryTemplate `json:"templates"`
+}
+
+// GalleryReport is the staleness report of the gallery templates across the fleet.
+type GalleryReport struct {
+	Repositories []GalleryRepository `json:"repositories"`
+}
+
+// NewGalleryReport groups the gallery templates of the container versions by repository and flags the stale ones.
+func NewGalleryReport(versions []*tagmanager.ContainerVersion) *GalleryReport {
+	repositories := make(map[string]*GalleryRepository)
+	var keys []string
+	for _, cv := range versions {
+		containerName := ""
+		if cv.Container != nil {
+			containerName = cv.Container.Name
+		}
+
+		for _, template := range cv.CustomTemplate {
+			gr := template.GalleryReference
+			if gr == nil {
+				continue
+			}
+
+			key := strings.Join([]string{gr.Host, gr.Owner, gr.Repository}, "/")
+			repo, ok := repositories[key]
+			if !ok {
+				repo = &GalleryRepository{Host: gr.Host, Owner: gr.Owner, Repository: gr.Repository}
+				repositories[key] = repo
+				keys = append(keys, key)
+			}
+
+			repo.Templates = append(repo.Templates, GalleryTemplate{
+				AccountID:     cv.AccountId,
+				ContainerID:   cv.ContainerId,
+				ContainerName: containerName,
+				TemplateID:    template.TemplateId,
+				Name:          template.Name,
+				Version:       gr.Version,
+				Signature:     gr.Signature,
+				IsModified:    gr.IsModified,
+				TagManagerURL: template.TagManagerUrl,
+			})
+		}
+	}
+
+	sort.Strings(keys)
+
+	rv := &GalleryReport{}
+	for _, key := range keys {
+		repo := repositories[key]
+		repo.Signature, repo.Signatures = commonSignature(repo.Templates)
+
+		for i := range repo.Templates {
+			repo.Templates[i].SignatureDrift = repo.Templates[i].Signature != repo.Signature
+			if repo.Templates[i].Stale() {
+				repo.Stale++
+			}
+		}
+
+		rv.Repositories = append(rv.Repositories, *repo)
+	}
+
+	return rv
+}
+
+// commonSignature returns the signature most templates are pinned to, ties going to the smallest one, and all the distinct signatures.
+func commonSignature(templates []GalleryTemplate) (string, []string) {
+	counts := make(map[string]int)
+	for _, t := range templates {
+		counts[t.Signature]++
+	}
+
+	signatures := make([]string, 0, len(counts))
+	for s := range counts {
+		signatures = append(signatures, s)
+	}
+	sort.Strings(signatures)
+
+	common := signatures[0]
+	for _, s := range signatures[1:] {
+		if counts[s] > counts[common] {
+			common = s
+		}
+	}
+
+	return common, signatures
+}
+
+// Records returns one record per installed gallery template.
+func (r *GalleryReport) Records() [][]string {
+	rv := [][]string{{
+		"host", "owner", "repository", "account_id", "container_id", "container_name", "template_id", "name",
+		"version", "signature", "fleet_signature", "is_modified", "signature_drift", "tag_manager_url",
+	}}
+
+	for _, repo := range r.Repositories {
+		for _, t := range repo.Templates {
+			rv = append(rv, []string{
+				repo.Host, repo.Owner, repo.Repository, t.AccountID, t.ContainerID, t.ContainerName, t.TemplateID, t.Name,
+				t.Version, t.Signature, repo.Signature, strconv.FormatBool(t.IsModified), strconv.FormatBool(t.SignatureDrift), t.TagManagerURL,
+			})
+		}
+	}
+
+	return rv
+}
@@ -0,0 +1,39 @@
+package fleet
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommonSignature(t *testing.T) {
+	tests := []struct {
+		name           string
+		signatures     []string
+		wantCommon     string
+		wantSignatures []string
+	}{
+		{"single template", []string{"b"}, "b", []string{"b"}},
+		{"same signature everywhere", []string{"a", "a", "a"}, "a", []string{"a"}},
+		{"most common signature", []string{"a", "c", "c", "b"}, "c", []string{"a", "b", "c"}},
+		{"tie goes to the smallest", []string{"c", "b", "c", "b"}, "b", []string{"b", "c"}},
+		{"unpinned templates count as a signature", []string{"", "", "a"}, "", []string{"", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates := make([]GalleryTemplate, 0, len(tt.signatures))
+			for _, s := range tt.signatures {
+				templates = append(templates, GalleryTemplate{Signature: s})
+			}
+
+			common, signatures := commonSignature(templates)
+			if common != tt.wantCommon {
+				t.Errorf("common signature = %q, want %q", common, tt.wantCommon)
+			}
+
+			if !reflect.DeepEqual(signatures, tt.wantSignatures) {
+				t.Errorf("signatures = %v, want %v", signatures, tt.wantSignatures)
+			}
+		})
+	}
+}
@@ -0,0 +1,620 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+// ImportMode decides what happens to entities of the import that already exist in the workspace under the same name and type.
+type ImportMode string
+
+const (
+	// ImportModeMerge keeps the existing entities and only creates the missing ones.
+	ImportModeMerge ImportMode = "merge"
+	// ImportModeOverwrite replaces the existing entities with the imported ones.
+	ImportModeOverwrite ImportMode = "overwrite"
+
+	ImportActionCreated = "created"
+	ImportActionUpdated = "updated"
+	ImportActionSkipped = "skipped"
+	// ImportActionUnchanged is reported in overwrite mode for existing entities already matching the imported ones.
+	ImportActionUnchanged = "unchanged"
+	// ImportActionPending is reported for the entities left unimported when the import fails.
+	ImportActionPending = "pending"
+	// ImportActionConflict is reported for entities sharing their name with an entity of another type in the workspace.
+	ImportActionConflict = "conflict"
+
+	// plannedIDPrefix prefixes the source id of the entities a dry run would create, as they have no target id yet.
+	plannedIDPrefix = "new:"
+
+	customTemplateTypePrefix = "cvt_"
+	triggerGroupType         = "triggerGroup"
+	triggerReferenceType     = "triggerReference"
+)
+
+// ParseImportMode returns the import mode named by s.
+func ParseImportMode(s string) (ImportMode, error) {
+	switch ImportMode(s) {
+	case ImportModeMerge, ImportModeOverwrite:
+		return ImportMode(s), nil
+	default:
+		return "", fmt.Errorf("googletagmanager-fleet: invalid import mode %q, expected %s or %s", s, ImportModeMerge, ImportModeOverwrite)
+	}
+}
+
+// ImportAction is what the import did, or would do in a dry run, to an entity of the workspace.
+type ImportAction struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	Action   string `json:"action"`
+}
+
+// importer creates the entities of a container version in a workspace, remapping the ids the entities reference each other by.
+type importer struct {
+	client            *tagmanager.Service
+	mode              ImportMode
+	dryRun            bool
+	workspacePath     string
+	sourceContainerID string
+	targetContainerID string
+	existing          *tagmanager.ContainerVersion
+
+	folderIDs   map[string]string
+	templateIDs map[string]string
+	triggerIDs  map[string]string
+	// variables are the imported variables by source id, their trigger ids are only known once the triggers are imported
+	variables map[string]*tagmanager.Variable
+	// variableActions are the indexes of the actions of the imported variables, linking their triggers can turn them into updates
+	variableActions map[string]int
+
+	actions []ImportAction
+}
+
+// Import creates the built-in variables, folders, templates, variables, triggers and tags of the container version in the workspace.
+// Entities are matched to the existing ones of the workspace by name and type, the mode decides whether those are kept or overwritten.
+// Nothing is imported when an entity shares its name with an existing one of another type, as names are unique in a workspace.
+// A dry run reports the planned actions without changing the workspace, the entities it would create get a new:<source id> target id.
+// When the import fails the entities it did not get to are reported as pending.
+func Import(
+	ctx context.Context,
+	client *tagmanager.Service,
+	cv *tagmanager.ContainerVersion,
+	container *tagmanager.Container,
+	workspace *tagmanager.Workspace,
+	mode ImportMode,
+	dryRun bool,
+) ([]ImportAction, error) {
+	existing, err := WorkspaceVersion(ctx, client, container, workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	im := &importer{
+		client:            client,
+		mode:              mode,
+		dryRun:            dryRun,
+		workspacePath:     workspace.Path,
+		sourceContainerID: cv.ContainerId,
+		targetContainerID: container.ContainerId,
+		existing:          existing,
+		folderIDs:         make(map[string]string),
+		templateIDs:       make(map[string]string),
+		triggerIDs:        make(map[string]string),
+		variables:         make(map[string]*tagmanager.Variable),
+		variableActions:   make(map[string]int),
+	}
+
+	steps := []func(context.Context, *tagmanager.ContainerVersion) error{
+		im.checkConflicts,
+		im.importBuiltInVariables,
+		im.importFolders,
+		im.importTemplates,
+		im.importVariables,
+		im.importTriggers,
+		im.linkVariableTriggers,
+		im.importTags,
+	}
+
+	for _, step := range steps {
+		err := step(ctx, cv)
+		if err != nil {
+			im.recordPending(cv)
+			return im.actions, err
+		}
+	}
+
+	return im.actions, nil
+}
+
+func (im *importer) record(kind, name, sourceID, targetID, action string) {
+	im.actions = append(im.actions, ImportAction{Kind: kind, Name: name, SourceID: sourceID, TargetID: targetID, Action: action})
+}
+
+// entityKey is the key entities are matched by, names are only unique among entities of the same type.
+func entityKey(name, entityType string) string {
+	return entityType + "/" + name
+}
+
+// checkConflicts reports the variables, triggers and tags sharing their name with an entity of another type in the workspace.
+// Custom template types are compared once mapped to the templates of the workspace with the same name.
+func (im *importer) checkConflicts(_ context.Context, cv *tagmanager.ContainerVersion) error {
+	templates := make(map[string]string, len(im.existing.CustomTemplate))
+	for _, t := range im.existing.CustomTemplate {
+		templates[t.Name] = t.TemplateId
+	}
+
+	for _, src := range cv.CustomTemplate {
+		if id, ok := templates[src.Name]; ok {
+			im.templateIDs[src.TemplateId] = id
+		}
+	}
+
+	types := make(map[string]map[string]string)
+	add := func(kind, name, entityType string) {
+		if types[kind] == nil {
+			types[kind] = make(map[string]string)
+		}
+
+		types[kind][name] = entityType
+	}
+
+	for _, v := range im.existing.Variable {
+		add("variable", v.Name, v.Type)
+	}
+	for _, t := range im.existing.Trigger {
+		add("trigger", t.Name, t.Type)
+	}
+	for _, t := range im.existing.Tag {
+		add("tag", t.Name, t.Type)
+	}
+
+	conflicts := 0
+	check := func(kind, name, sourceID, entityType string) {
+		if existing, ok := types[kind][name]; ok && existing != entityType {
+			im.record(kind, name, sourceID, "", ImportActionConflict)
+			conflicts++
+		}
+	}
+
+	for _, v := range cv.Variable {
+		check("variable", v.Name, v.VariableId, im.entityType(v.Type))
+	}
+	for _, t := range cv.Trigger {
+		check("trigger", t.Name, t.TriggerId, t.Type)
+	}
+	for _, t := range cv.Tag {
+		check("tag", t.Name, t.TagId, im.entityType(t.Type))
+	}
+
+	if conflicts > 0 {
+		return fmt.Errorf("googletagmanager-fleet: %d entities share their name with an entity of another type in the workspace, nothing was imported", conflicts)
+	}
+
+	return nil
+}
+
+// recordPending records the entities of the version that have no action yet.
+func (im *importer) recordPending(cv *tagmanager.ContainerVersion) {
+	done := make(map[string]struct{}, len(im.actions))
+	for _, a := range im.actions {
+		done[a.Kind+"/"+a.SourceID] = struct{}{}
+	}
+
+	pending := func(kind, name, sourceID string) {
+		if _, ok := done[kind+"/"+sourceID]; !ok {
+			im.record(kind, name, sourceID, "", ImportActionPending)
+		}
+	}
+
+	for _, bv := range cv.BuiltInVariable {
+		pending("built_in_variable", bv.Name, bv.Type)
+	}
+	for _, f := range cv.Folder {
+		pending("folder", f.Name, f.FolderId)
+	}
+	for _, t := range cv.CustomTemplate {
+		pending("template", t.Name, t.TemplateId)
+	}
+	for _, v := range cv.Variable {
+		pending("variable", v.Name, v.VariableId)
+	}
+	for _, t := range cv.Trigger {
+		pending("trigger", t.Name, t.TriggerId)
+	}
+	for _, t := range cv.Tag {
+		pending("tag", t.Name, t.TagId)
+	}
+}
+
+func (im *importer) importBuiltInVariables(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	enabled := make(map[string]struct{}, len(im.existing.BuiltInVariable))
+	for _, bv := range im.existing.BuiltInVariable {
+		enabled[bv.Type] = struct{}{}
+	}
+
+	var missing []string
+	for _, bv := range cv.BuiltInVariable {
+		if _, ok := enabled[bv.Type]; ok {
+			im.record("built_in_variable", bv.Name, bv.Type, bv.Type, ImportActionSkipped)
+			continue
+		}
+
+		missing = append(missing, bv.Type)
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if im.dryRun {
+		for _, bv := range cv.BuiltInVariable {
+			if _, ok := enabled[bv.Type]; !ok {
+				im.record("built_in_variable", bv.Name, bv.Type, bv.Type, ImportActionCreated)
+			}
+		}
+
+		return nil
+	}
+
+	created, err := im.client.Accounts.Containers.Workspaces.BuiltInVariables.Create(im.workspacePath).Type(missing...).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("googletagmanager-fleet: failed to enable built-in variables: %w", err)
+	}
+
+	for _, bv := range created.BuiltInVariable {
+		im.record("built_in_variable", bv.Name, bv.Type, bv.Type, ImportActionCreated)
+	}
+
+	return nil
+}
+
+func (im *importer) importFolders(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	existing := make(map[string]*tagmanager.Folder, len(im.existing.Folder))
+	for _, f := range im.existing.Folder {
+		existing[f.Name] = f
+	}
+
+	folders := im.client.Accounts.Containers.Workspaces.Folders
+	for _, src := range cv.Folder {
+		folder := &tagmanager.Folder{Name: src.Name, Notes: src.Notes}
+
+		var err error
+		action := ImportActionCreated
+		if e, ok := existing[src.Name]; ok {
+			action = im.existingAction(e, folder)
+			switch {
+			case action != ImportActionUpdated:
+				folder = e
+			case im.dryRun:
+				folder.FolderId = e.FolderId
+			default:
+				folder, err = folders.Update(e.Path, folder).Context(ctx).Do()
+			}
+		} else if im.dryRun {
+			folder.FolderId = plannedIDPrefix + src.FolderId
+		} else {
+			folder, err = folders.Create(im.workspacePath, folder).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to import folder %s: %w", src.Name, err)
+		}
+
+		im.folderIDs[src.FolderId] = folder.FolderId
+		im.record("folder", src.Name, src.FolderId, folder.FolderId, action)
+	}
+
+	return nil
+}
+
+func (im *importer) importTemplates(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	existing := make(map[string]*tagmanager.CustomTemplate, len(im.existing.CustomTemplate))
+	for _, t := range im.existing.CustomTemplate {
+		existing[t.Name] = t
+	}
+
+	templates := im.client.Accounts.Containers.Workspaces.Templates
+	for _, src := range cv.CustomTemplate {
+		template := &tagmanager.CustomTemplate{Name: src.Name, TemplateData: src.TemplateData}
+
+		var err error
+		action := ImportActionCreated
+		if e, ok := existing[src.Name]; ok {
+			action = im.existingAction(e, template)
+			switch {
+			case action != ImportActionUpdated:
+				template = e
+			case im.dryRun:
+				template.TemplateId = e.TemplateId
+			default:
+				template, err = templates.Update(e.Path, template).Context(ctx).Do()
+			}
+		} else if im.dryRun {
+			template.TemplateId = plannedIDPrefix + src.TemplateId
+		} else {
+			template, err = templates.Create(im.workspacePath, template).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to import template %s: %w", src.Name, err)
+		}
+
+		im.templateIDs[src.TemplateId] = template.TemplateId
+		im.record("template", src.Name, src.TemplateId, template.TemplateId, action)
+	}
+
+	return nil
+}
+
+func (im *importer) importVariables(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	existing := make(map[string]*tagmanager.Variable, len(im.existing.Variable))
+	for _, v := range im.existing.Variable {
+		existing[entityKey(v.Name, v.Type)] = v
+	}
+
+	variables := im.client.Accounts.Containers.Workspaces.Variables
+	for _, src := range cv.Variable {
+		variable := &tagmanager.Variable{
+			Name:            src.Name,
+			Type:            im.entityType(src.Type),
+			Notes:           src.Notes,
+			Parameter:       src.Parameter,
+			FormatValue:     src.FormatValue,
+			ScheduleStartMs: src.ScheduleStartMs,
+			ScheduleEndMs:   src.ScheduleEndMs,
+			ParentFolderId:  im.folderIDs[src.ParentFolderId],
+		}
+
+		var err error
+		action := ImportActionCreated
+		if e, ok := existing[entityKey(src.Name, variable.Type)]; ok {
+			// the triggers are linked once they are imported, until then the existing ones are kept so they don't count as a change
+			variable.EnablingTriggerId = e.EnablingTriggerId
+			variable.DisablingTriggerId = e.DisablingTriggerId
+			action = im.existingAction(e, variable)
+			switch {
+			case action != ImportActionUpdated:
+				variable = e
+			case im.dryRun:
+				variable.VariableId = e.VariableId
+			default:
+				variable, err = variables.Update(e.Path, variable).Context(ctx).Do()
+			}
+		} else if im.dryRun {
+			variable.VariableId = plannedIDPrefix + src.VariableId
+		} else {
+			variable, err = variables.Create(im.workspacePath, variable).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to import variable %s: %w", src.Name, err)
+		}
+
+		if action != ImportActionSkipped {
+			im.variables[src.VariableId] = variable
+			im.variableActions[src.VariableId] = len(im.actions)
+		}
+		im.record("variable", src.Name, src.VariableId, variable.VariableId, action)
+	}
+
+	return nil
+}
+
+// importTriggers imports the trigger groups last as they reference the other triggers by id.
+func (im *importer) importTriggers(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	existing := make(map[string]*tagmanager.Trigger, len(im.existing.Trigger))
+	for _, t := range im.existing.Trigger {
+		existing[entityKey(t.Name, t.Type)] = t
+	}
+
+	var ordered []*tagmanager.Trigger
+	var groups []*tagmanager.Trigger
+	for _, t := range cv.Trigger {
+		if t.Type == triggerGroupType {
+			groups = append(groups, t)
+			continue
+		}
+
+		ordered = append(ordered, t)
+	}
+	ordered = append(ordered, groups...)
+
+	triggers := im.client.Accounts.Containers.Workspaces.Triggers
+	for _, src := range ordered {
+		trigger := *src
+		trigger.AccountId = ""
+		trigger.ContainerId = ""
+		trigger.WorkspaceId = ""
+		trigger.TriggerId = ""
+		trigger.Path = ""
+		trigger.Fingerprint = ""
+		trigger.TagManagerUrl = ""
+		trigger.ParentFolderId = im.folderIDs[src.ParentFolderId]
+		trigger.Parameter = remapTriggerReferences(src.Parameter, im.triggerIDs)
+
+		imported := &trigger
+		var err error
+		action := ImportActionCreated
+		if e, ok := existing[entityKey(src.Name, imported.Type)]; ok {
+			action = im.existingAction(e, imported)
+			switch {
+			case action != ImportActionUpdated:
+				imported = e
+			case im.dryRun:
+				imported.TriggerId = e.TriggerId
+			default:
+				imported, err = triggers.Update(e.Path, imported).Context(ctx).Do()
+			}
+		} else if im.dryRun {
+			imported.TriggerId = plannedIDPrefix + src.TriggerId
+		} else {
+			imported, err = triggers.Create(im.workspacePath, imported).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to import trigger %s: %w", src.Name, err)
+		}
+
+		im.triggerIDs[src.TriggerId] = imported.TriggerId
+		im.record("trigger", src.Name, src.TriggerId, imported.TriggerId, action)
+	}
+
+	return nil
+}
+
+// linkVariableTriggers sets the enabling and disabling triggers of the imported variables once the triggers have their new ids.
+// Variables are only updated when their triggers differ, an unchanged variable whose triggers change is reported as updated.
+func (im *importer) linkVariableTriggers(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	variables := im.client.Accounts.Containers.Workspaces.Variables
+	for _, src := range cv.Variable {
+		variable, ok := im.variables[src.VariableId]
+		if !ok {
+			continue
+		}
+
+		enabling := remapIDs(src.EnablingTriggerId, im.triggerIDs)
+		disabling := remapIDs(src.DisablingTriggerId, im.triggerIDs)
+		if slices.Equal(enabling, variable.EnablingTriggerId) && slices.Equal(disabling, variable.DisablingTriggerId) {
+			continue
+		}
+
+		variable.EnablingTriggerId = enabling
+		variable.DisablingTriggerId = disabling
+
+		if a := &im.actions[im.variableActions[src.VariableId]]; a.Action == ImportActionUnchanged {
+			a.Action = ImportActionUpdated
+		}
+
+		if im.dryRun {
+			continue
+		}
+
+		_, err := variables.Update(variable.Path, variable).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to link triggers of variable %s: %w", src.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (im *importer) importTags(ctx context.Context, cv *tagmanager.ContainerVersion) error {
+	existing := make(map[string]*tagmanager.Tag, len(im.existing.Tag))
+	for _, t := range im.existing.Tag {
+		existing[entityKey(t.Name, t.Type)] = t
+	}
+
+	tags := im.client.Accounts.Containers.Workspaces.Tags
+	for _, src := range cv.Tag {
+		tag := *src
+		tag.AccountId = ""
+		tag.ContainerId = ""
+		tag.WorkspaceId = ""
+		tag.TagId = ""
+		tag.Path = ""
+		tag.Fingerprint = ""
+		tag.TagManagerUrl = ""
+		tag.Type = im.entityType(src.Type)
+		tag.ParentFolderId = im.folderIDs[src.ParentFolderId]
+		tag.FiringTriggerId = remapIDs(src.FiringTriggerId, im.triggerIDs)
+		tag.BlockingTriggerId = remapIDs(src.BlockingTriggerId, im.triggerIDs)
+
+		imported := &tag
+		var err error
+		action := ImportActionCreated
+		if e, ok := existing[entityKey(src.Name, imported.Type)]; ok {
+			action = im.existingAction(e, imported)
+			switch {
+			case action != ImportActionUpdated:
+				imported = e
+			case im.dryRun:
+				imported.TagId = e.TagId
+			default:
+				imported, err = tags.Update(e.Path, imported).Context(ctx).Do()
+			}
+		} else if im.dryRun {
+			imported.TagId = plannedIDPrefix + src.TagId
+		} else {
+			imported, err = tags.Create(im.workspacePath, imported).Context(ctx).Do()
+		}
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to import tag %s: %w", src.Name, err)
+		}
+
+		im.record("tag", src.Name, src.TagId, imported.TagId, action)
+	}
+
+	return nil
+}
+
+// existingAction returns what to do with an entity of the workspace sharing its name with the imported entity.
+func (im *importer) existingAction(existing, imported any) string {
+	if im.mode != ImportModeOverwrite {
+		return ImportActionSkipped
+	}
+
+	if sameEntity(existing, imported) {
+		return ImportActionUnchanged
+	}
+
+	return ImportActionUpdated
+}
+
+// entityType returns the type of a tag or variable in the target container.
+// Types of custom templates are named after the container and the id of the template, cvt_<container id>_<template id>.
+func (im *importer) entityType(t string) string {
+	templateID, ok := strings.CutPrefix(t, customTemplateTypePrefix+im.sourceContainerID+"_")
+	if !ok {
+		return t
+	}
+
+	targetID, ok := im.templateIDs[templateID]
+	if !ok {
+		return t
+	}
+
+	return customTemplateTypePrefix + im.targetContainerID + "_" + targetID
+}
+
+// remapIDs returns the ids mapped to their new value, ids without a mapping such as the ones of built-in triggers are kept.
+func remapIDs(ids []string, mapping map[string]string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rv := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if mapped, ok := mapping[id]; ok {
+			id = mapped
+		}
+
+		rv = append(rv, id)
+	}
+
+	return rv
+}
+
+// remapTriggerReferences returns a copy of the parameters with the trigger references of trigger groups mapped to their new id.
+func remapTriggerReferences(parameters []*tagmanager.Parameter, mapping map[string]string) []*tagmanager.Parameter {
+	if len(parameters) == 0 {
+		return nil
+	}
+
+	rv := make([]*tagmanager.Parameter, 0, len(parameters))
+	for _, p := range parameters {
+		c := *p
+		if c.Type == triggerReferenceType {
+			if mapped, ok := mapping[c.Value]; ok {
+				c.Value = mapped
+			}
+		}
+
+		c.List = remapTriggerReferences(p.List, mapping)
+		c.Map = remapTriggerReferences(p.Map, mapping)
+		rv = append(rv, &c)
+	}
+
+	return rv
+}
@@ -0,0 +1,222 @@
+package fleet
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+func TestRemapIDs(t *testing.T) {
+	mapping := map[string]string{"1": "101", "2": "102"}
+
+	tests := []struct {
+		name string
+		ids  []string
+		want []string
+	}{
+		{"nil", nil, nil},
+		{"empty", []string{}, nil},
+		{"mapped", []string{"1", "2"}, []string{"101", "102"}},
+		{"built-in trigger ids are kept", []string{"2147479553", "1"}, []string{"2147479553", "101"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remapIDs(tt.ids, mapping); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("remapIDs(%v) = %v, want %v", tt.ids, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemapTriggerReferences(t *testing.T) {
+	mapping := map[string]string{"7": "107", "8": "108"}
+
+	src := []*tagmanager.Parameter{
+		{Key: "triggerIds", Type: "list", List: []*tagmanager.Parameter{
+			{Type: triggerReferenceType, Value: "7"},
+			{Type: triggerReferenceType, Value: "9"},
+			{Type: "map", Map: []*tagmanager.Parameter{
+				{Key: "ref", Type: triggerReferenceType, Value: "8"},
+				{Key: "text", Type: "template", Value: "7"},
+			}},
+		}},
+		{Key: "name", Type: "template", Value: "8"},
+	}
+
+	want := []*tagmanager.Parameter{
+		{Key: "triggerIds", Type: "list", List: []*tagmanager.Parameter{
+			{Type: triggerReferenceType, Value: "107"},
+			{Type: triggerReferenceType, Value: "9"},
+			{Type: "map", Map: []*tagmanager.Parameter{
+				{Key: "ref", Type: triggerReferenceType, Value: "108"},
+				{Key: "text", Type: "template", Value: "7"},
+			}},
+		}},
+		{Key: "name", Type: "template", Value: "8"},
+	}
+
+	got := remapTriggerReferences(src, mapping)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("remapTriggerReferences() = %+v, want %+v", got, want)
+	}
+
+	if src[0].List[0].Value != "7" || src[0].List[2].Map[0].Value != "8" {
+		t.Errorf("remapTriggerReferences() modified the source parameters")
+	}
+
+	if got := remapTriggerReferences(nil, mapping); got != nil {
+		t.Errorf("remapTriggerReferences(nil) = %v, want nil", got)
+	}
+}
+
+func TestEntityType(t *testing.T) {
+	im := &importer{
+		sourceContainerID: "11",
+		targetContainerID: "22",
+		templateIDs:       map[string]string{"5": "50"},
+	}
+
+	tests := []struct {
+		name string
+		t    string
+		want string
+	}{
+		{"built-in type", "html", "html"},
+		{"imported template", "cvt_11_5", "cvt_22_50"},
+		{"template that was not imported", "cvt_11_6", "cvt_11_6"},
+		{"template of another container", "cvt_33_5", "cvt_33_5"},
+		{"container id prefix of another container", "cvt_115_5", "cvt_115_5"},
+		{"gallery template", "cvt_KDDGR", "cvt_KDDGR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := im.entityType(tt.t); got != tt.want {
+				t.Errorf("entityType(%q) = %q, want %q", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordPending(t *testing.T) {
+	cv := &tagmanager.ContainerVersion{
+		Folder:  []*tagmanager.Folder{{FolderId: "1", Name: "Done"}},
+		Trigger: []*tagmanager.Trigger{{TriggerId: "2", Name: "Failed"}},
+		Tag:     []*tagmanager.Tag{{TagId: "3", Name: "Left"}},
+	}
+
+	im := &importer{}
+	im.record("folder", "Done", "1", "101", ImportActionCreated)
+	im.recordPending(cv)
+
+	want := []ImportAction{
+		{Kind: "folder", Name: "Done", SourceID: "1", TargetID: "101", Action: ImportActionCreated},
+		{Kind: "trigger", Name: "Failed", SourceID: "2", Action: ImportActionPending},
+		{Kind: "tag", Name: "Left", SourceID: "3", Action: ImportActionPending},
+	}
+
+	if !reflect.DeepEqual(im.actions, want) {
+		t.Errorf("actions = %+v, want %+v", im.actions, want)
+	}
+}
+
+func TestCheckConflicts(t *testing.T) {
+	im := &importer{
+		sourceContainerID: "11",
+		targetContainerID: "22",
+		templateIDs:       make(map[string]string),
+		existing: &tagmanager.ContainerVersion{
+			CustomTemplate: []*tagmanager.CustomTemplate{{TemplateId: "9", Name: "Vendor"}},
+			Variable:       []*tagmanager.Variable{{VariableId: "1", Name: "Host", Type: "c"}},
+			Trigger:        []*tagmanager.Trigger{{TriggerId: "2", Name: "Clicks", Type: "click"}},
+			Tag: []*tagmanager.Tag{
+				{TagId: "3", Name: "Pixel", Type: "html"},
+				{TagId: "4", Name: "Vendor Tag", Type: "cvt_22_9"},
+			},
+		},
+	}
+
+	cv := &tagmanager.ContainerVersion{
+		CustomTemplate: []*tagmanager.CustomTemplate{{TemplateId: "5", Name: "Vendor"}},
+		Variable:       []*tagmanager.Variable{{VariableId: "10", Name: "Host", Type: "v"}},
+		Trigger:        []*tagmanager.Trigger{{TriggerId: "20", Name: "Clicks", Type: "click"}},
+		Tag: []*tagmanager.Tag{
+			{TagId: "30", Name: "Pixel", Type: "img"},
+			{TagId: "40", Name: "Vendor Tag", Type: "cvt_11_5"},
+			{TagId: "50", Name: "New", Type: "html"},
+		},
+	}
+
+	err := im.checkConflicts(context.Background(), cv)
+	if err == nil {
+		t.Fatal("checkConflicts() returned no error, want one for the conflicts")
+	}
+
+	want := []ImportAction{
+		{Kind: "variable", Name: "Host", SourceID: "10", Action: ImportActionConflict},
+		{Kind: "tag", Name: "Pixel", SourceID: "30", Action: ImportActionConflict},
+	}
+
+	if !reflect.DeepEqual(im.actions, want) {
+		t.Errorf("actions = %+v, want %+v", im.actions, want)
+	}
+
+	if im.templateIDs["5"] != "9" {
+		t.Errorf("template 5 mapped to %q, want the existing template 9 of the same name", im.templateIDs["5"])
+	}
+}
+
+func TestLinkVariableTriggers(t *testing.T) {
+	client, err := tagmanager.NewService(context.Background(), option.WithoutAuthentication())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	im := &importer{
+		client:          client,
+		dryRun:          true,
+		triggerIDs:      map[string]string{"7": "100", "8": "200"},
+		variables:       make(map[string]*tagmanager.Variable),
+		variableActions: make(map[string]int),
+	}
+
+	same := &tagmanager.Variable{Name: "Same", EnablingTriggerId: []string{"100"}}
+	changed := &tagmanager.Variable{Name: "Changed", EnablingTriggerId: []string{"100"}}
+	none := &tagmanager.Variable{Name: "None"}
+	for id, v := range map[string]*tagmanager.Variable{"1": same, "2": changed, "3": none} {
+		im.variables[id] = v
+		im.variableActions[id] = len(im.actions)
+		im.record("variable", v.Name, id, id, ImportActionUnchanged)
+	}
+
+	cv := &tagmanager.ContainerVersion{
+		Variable: []*tagmanager.Variable{
+			{VariableId: "1", Name: "Same", EnablingTriggerId: []string{"7"}},
+			{VariableId: "2", Name: "Changed", EnablingTriggerId: []string{"7"}, DisablingTriggerId: []string{"8"}},
+			{VariableId: "3", Name: "None"},
+		},
+	}
+
+	err = im.linkVariableTriggers(context.Background(), cv)
+	if err != nil {
+		t.Fatalf("linkVariableTriggers() returned error: %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, a := range im.actions {
+		got[a.Name] = a.Action
+	}
+
+	want := map[string]string{"Same": ImportActionUnchanged, "Changed": ImportActionUpdated, "None": ImportActionUnchanged}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("actions = %v, want %v", got, want)
+	}
+
+	if !reflect.DeepEqual(changed.DisablingTriggerId, []string{"200"}) {
+		t.Errorf("disabling triggers = %v, want [200]", changed.DisablingTriggerId)
+	}
+}
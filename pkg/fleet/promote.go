@@ -0,0 +1,84 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+// PromoteResult is the outcome of promoting a container to another.
+type PromoteResult struct {
+	SourceVersionID string         `json:"source_version_id"`
+	Workspace       string         `json:"workspace"`
+	Actions         []ImportAction `json:"actions"`
+	// Version is the version created from the target workspace, it is left unpublished.
+	Version *tagmanager.ContainerVersion `json:"version,omitempty"`
+}
+
+// Changed reports whether the promotion created or updated any entity of the target workspace.
+func (r *PromoteResult) Changed() bool {
+	for _, a := range r.Actions {
+		if a.Action == ImportActionCreated || a.Action == ImportActionUpdated {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Promote brings the default workspace of the target container in line with the live version of the source container.
+// Entities are matched by kind, name and type, the ones missing or differing in the target are created or updated.
+// When createVersion is set and something changed a version is created from the workspace, publishing it is left to a human.
+func Promote(
+	ctx context.Context,
+	client *tagmanager.Service,
+	source *tagmanager.Container,
+	target *tagmanager.Container,
+	createVersion bool,
+	versionName string,
+) (*PromoteResult, error) {
+	cv, err := client.Accounts.Containers.Versions.Live(source.Path).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("googletagmanager-fleet: failed to get live version of container %s: %w", source.ContainerId, err)
+	}
+
+	workspace, err := FindWorkspace(ctx, client, target, "")
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &PromoteResult{
+		SourceVersionID: cv.ContainerVersionId,
+		Workspace:       workspace.Name,
+	}
+
+	rv.Actions, err = Import(ctx, client, cv, target, workspace, ImportModeOverwrite, false)
+	if err != nil {
+		return rv, err
+	}
+
+	if !createVersion || !rv.Changed() {
+		return rv, nil
+	}
+
+	if versionName == "" {
+		versionName = fmt.Sprintf("Promoted from %s version %s", source.PublicId, cv.ContainerVersionId)
+	}
+
+	created, err := client.Accounts.Containers.Workspaces.CreateVersion(workspace.Path, &tagmanager.CreateContainerVersionRequestVersionOptions{
+		Name:  versionName,
+		Notes: fmt.Sprintf("Promoted from container %s (%s) version %s", source.Name, source.PublicId, cv.ContainerVersionId),
+	}).Context(ctx).Do()
+	if err != nil {
+		return rv, fmt.Errorf("googletagmanager-fleet: failed to create version: %w", err)
+	}
+
+	if created.CompilerError {
+		return rv, fmt.Errorf("googletagmanager-fleet: the workspace of container %s has compiler errors, no version was created", target.ContainerId)
+	}
+
+	rv.Version = created.ContainerVersion
+
+	return rv, nil
+}
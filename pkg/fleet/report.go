@@ -0,0 +1,49 @@
+package fleet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// Report is the result of a fleet wide check that can be written as JSON or as CSV records.
+type Report interface {
+	// Records returns the CSV records of the report, starting with the header.
+	Records() [][]string
+}
+
+// ValidateFormat returns an error if the report output format is not supported.
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatJSON, FormatCSV:
+		return nil
+	default:
+		return fmt.Errorf("googletagmanager-fleet: unsupported output format %q, expected %s or %s", format, FormatJSON, FormatCSV)
+	}
+}
+
+// WriteReport writes the report to w in the format.
+func WriteReport(w io.Writer, format string, r Report) error {
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		err := cw.WriteAll(r.Records())
+		if err != nil {
+			return fmt.Errorf("googletagmanager-fleet: failed to write CSV report: %w", err)
+		}
+
+		return nil
+	default:
+		return ValidateFormat(format)
+	}
+}
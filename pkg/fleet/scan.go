@@ -0,0 +1,253 @@
+package fleet
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+const (
+	tagTypeCustomHTML  = "html"
+	tagTypeCustomImage = "img"
+
+	// FindingUnknownDomain is reported for urls loaded from a domain outside of the allowlist.
+	FindingUnknownDomain = "unknown_domain"
+	// FindingDynamicURL is reported for urls whose host can't be resolved statically, usually because it is built from variables.
+	FindingDynamicURL = "dynamic_url"
+)
+
+var (
+	// sourceAttribute matches the source attribute of the script, img and iframe elements of custom HTML.
+	sourceAttribute = regexp.MustCompile(`(?is)<(script|img|iframe)\b[^>]*?\bsrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	// inlineScript matches the body of the inline script elements of custom HTML.
+	inlineScript = regexp.MustCompile(`(?is)<script\b[^>]*>(.*?)</script\s*>`)
+	// createElement matches the elements created by a script, they give the element of the src assignments that follow.
+	createElement = regexp.MustCompile(`(?i)createElement\(\s*["'](script|img|iframe)["']\s*\)`)
+	// srcAssignment matches the assignment of a string or of an expression to the src property of an element.
+	srcAssignment = regexp.MustCompile("(?i)\\.src\\s*=\\s*(?:\"([^\"]*)\"|'([^']*)'|`([^`]*)`|([^=;\\n][^;\\n]*))")
+	// quotedURL matches the absolute and protocol relative urls quoted in a script.
+	quotedURL = regexp.MustCompile("[\"'`]((?:https?:)?//[^\"'`\\s]+)[\"'`]")
+)
+
+// elementInlineScript is the element of urls quoted in an inline script without being assigned to an element.
+const elementInlineScript = "inline_script"
+
+// ScriptFinding is a url loaded by a custom HTML or custom image tag of a live version that is not in the allowlist.
+type ScriptFinding struct {
+	AccountID     string `json:"account_id"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	TagID         string `json:"tag_id"`
+	TagName       string `json:"tag_name"`
+	TagType       string `json:"tag_type"`
+	Element       string `json:"element"`
+	URL           string `json:"url"`
+	Host          string `json:"host"`
+	Reason        string `json:"reason"`
+	TagManagerURL string `json:"tag_manager_url"`
+}
+
+// ScanReport lists the findings of the scan of the custom HTML and custom image tags.
+type ScanReport struct {
+	AllowedDomains []string        `json:"allowed_domains"`
+	ScannedTags    int             `json:"scanned_tags"`
+	Findings       []ScriptFinding `json:"findings"`
+}
+
+// NewScanReport scans the custom HTML and custom image tags of the container versions for urls outside of the allowed domains.
+// A domain allows itself and all its subdomains.
+func NewScanReport(versions []*tagmanager.ContainerVersion, allowedDomains []string) *ScanReport {
+	rv := &ScanReport{}
+	for _, d := range allowedDomains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), ".")
+		if d != "" {
+			rv.AllowedDomains = append(rv.AllowedDomains, d)
+		}
+	}
+
+	for _, cv := range versions {
+		containerName := ""
+		if cv.Container != nil {
+			containerName = cv.Container.Name
+		}
+
+		for _, tag := range cv.Tag {
+			var sources []tagSource
+			switch tag.Type {
+			case tagTypeCustomHTML:
+				sources = htmlSources(parameterValue(tag.Parameter, "html"))
+			case tagTypeCustomImage:
+				if u := parameterValue(tag.Parameter, "url"); u != "" {
+					sources = []tagSource{{element: "img", url: u}}
+				}
+			default:
+				continue
+			}
+
+			rv.ScannedTags++
+
+			for _, src := range sources {
+				host, reason := rv.check(src.url)
+				if src.dynamic {
+					host, reason = "", FindingDynamicURL
+				}
+
+				if reason == "" {
+					continue
+				}
+
+				rv.Findings = append(rv.Findings, ScriptFinding{
+					AccountID:     cv.AccountId,
+					ContainerID:   cv.ContainerId,
+					ContainerName: containerName,
+					TagID:         tag.TagId,
+					TagName:       tag.Name,
+					TagType:       tag.Type,
+					Element:       src.element,
+					URL:           src.url,
+					Host:          host,
+					Reason:        reason,
+					TagManagerURL: tag.TagManagerUrl,
+				})
+			}
+		}
+	}
+
+	return rv
+}
+
+// check returns the host of the url and the reason it is reported, or an empty reason if the url is allowed.
+func (r *ScanReport) check(rawURL string) (string, string) {
+	if strings.Contains(rawURL, "{{") {
+		return "", FindingDynamicURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", FindingDynamicURL
+	}
+
+	// relative urls load from the site the container is installed on
+	if u.Host == "" && u.Scheme == "" {
+		return "", ""
+	}
+
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return "", FindingDynamicURL
+	}
+
+	for _, d := range r.AllowedDomains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return host, ""
+		}
+	}
+
+	return host, FindingUnknownDomain
+}
+
+// Records returns one record per finding.
+func (r *ScanReport) Records() [][]string {
+	rv := [][]string{{
+		"account_id", "container_id", "container_name", "tag_id", "tag_name", "tag_type", "element", "url", "host", "reason", "tag_manager_url",
+	}}
+
+	for _, f := range r.Findings {
+		rv = append(rv, []string{
+			f.AccountID, f.ContainerID, f.ContainerName, f.TagID, f.TagName, f.TagType, f.Element, f.URL, f.Host, f.Reason, f.TagManagerURL,
+		})
+	}
+
+	return rv
+}
+
+type tagSource struct {
+	element string
+	url     string
+	// dynamic is set when the url is an expression rather than a string, it can't be checked statically.
+	dynamic bool
+}
+
+// htmlSources returns the urls loaded by the script, img and iframe elements of the custom HTML,
+// including the ones inline scripts assign to the src of the elements they create and the urls they quote.
+func htmlSources(html string) []tagSource {
+	var rv []tagSource
+	for _, m := range sourceAttribute.FindAllStringSubmatch(html, -1) {
+		src := m[2] + m[3] + m[4]
+		if strings.TrimSpace(src) == "" {
+			continue
+		}
+
+		rv = append(rv, tagSource{element: strings.ToLower(m[1]), url: strings.TrimSpace(src)})
+	}
+
+	for _, m := range inlineScript.FindAllStringSubmatch(html, -1) {
+		rv = append(rv, scriptSources(m[1])...)
+	}
+
+	return rv
+}
+
+// scriptSources returns the urls assigned to the src of elements and the other urls quoted in the script.
+// An assignment is attributed to the element created last before it, or to a script when none was created.
+func scriptSources(script string) []tagSource {
+	created := createElement.FindAllStringSubmatchIndex(script, -1)
+
+	var rv []tagSource
+	seen := make(map[string]struct{})
+	for _, m := range srcAssignment.FindAllStringSubmatchIndex(script, -1) {
+		element := "script"
+		for _, c := range created {
+			if c[0] < m[0] {
+				element = strings.ToLower(script[c[2]:c[3]])
+			}
+		}
+
+		src := tagSource{element: element}
+		switch {
+		case m[2] >= 0:
+			src.url = script[m[2]:m[3]]
+		case m[4] >= 0:
+			src.url = script[m[4]:m[5]]
+		case m[6] >= 0:
+			src.url = script[m[6]:m[7]]
+			// template literals with substitutions are built at runtime
+			src.dynamic = strings.Contains(src.url, "${")
+		default:
+			src.url = script[m[8]:m[9]]
+			src.dynamic = true
+		}
+
+		src.url = strings.TrimSpace(src.url)
+		if src.url == "" {
+			continue
+		}
+
+		seen[src.url] = struct{}{}
+		rv = append(rv, src)
+	}
+
+	for _, m := range quotedURL.FindAllStringSubmatch(script, -1) {
+		if _, ok := seen[m[1]]; ok {
+			continue
+		}
+
+		seen[m[1]] = struct{}{}
+		rv = append(rv, tagSource{element: elementInlineScript, url: m[1]})
+	}
+
+	return rv
+}
+
+// parameterValue returns the value of the top level parameter with the key.
+func parameterValue(parameters []*tagmanager.Parameter, key string) string {
+	for _, p := range parameters {
+		if p.Key == key {
+			return p.Value
+		}
+	}
+
+	return ""
+}
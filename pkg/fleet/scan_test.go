@@ -0,0 +1,184 @@
+package fleet
+
+import (
+	"reflect"
+	"testing"
+
+	"google.golang.org/api/tagmanager/v2"
+)
+
+func TestHTMLSources(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want []tagSource
+	}{
+		{
+			name: "script src attribute",
+			html: `<script src="https://cdn.example.com/a.js"></script>`,
+			want: []tagSource{{element: "script", url: "https://cdn.example.com/a.js"}},
+		},
+		{
+			name: "single quoted and unquoted attributes",
+			html: `<img src='https://pixel.example.com/p.gif'><IFRAME SRC=https://frame.example.com/f></IFRAME>`,
+			want: []tagSource{
+				{element: "img", url: "https://pixel.example.com/p.gif"},
+				{element: "iframe", url: "https://frame.example.com/f"},
+			},
+		},
+		{
+			name: "src assigned to a created script",
+			html: `<script>
+var s = document.createElement('script');
+s.src = "https://tracker.example.net/t.js";
+document.head.appendChild(s);
+</script>`,
+			want: []tagSource{{element: "script", url: "https://tracker.example.net/t.js"}},
+		},
+		{
+			name: "src assigned to the element created last",
+			html: `<script>
+var s = document.createElement("script"); s.src = 'https://a.example.com/a.js';
+var i = document.createElement("img"); i.src = "https://b.example.com/b.gif";
+</script>`,
+			want: []tagSource{
+				{element: "script", url: "https://a.example.com/a.js"},
+				{element: "img", url: "https://b.example.com/b.gif"},
+			},
+		},
+		{
+			name: "src assigned an expression",
+			html: `<script>var s = document.createElement('script'); s.src = base + '/t.js';</script>`,
+			want: []tagSource{{element: "script", url: "base + '/t.js'", dynamic: true}},
+		},
+		{
+			name: "src assigned a template literal",
+			html: "<script>s.src = `https://${host}/t.js`; i.src = `https://static.example.com/i.gif`;</script>",
+			want: []tagSource{
+				{element: "script", url: "https://${host}/t.js", dynamic: true},
+				{element: "script", url: "https://static.example.com/i.gif"},
+			},
+		},
+		{
+			name: "src comparison is not an assignment",
+			html: `<script>if (s.src == "https://a.example.com/a.js") { ok(); }</script>`,
+			want: []tagSource{{element: elementInlineScript, url: "https://a.example.com/a.js"}},
+		},
+		{
+			name: "quoted urls in an inline script",
+			html: `<script>
+fetch("https://api.example.org/collect");
+navigator.sendBeacon('//beacon.example.org/b', data);
+var relative = "/local/path";
+</script>`,
+			want: []tagSource{
+				{element: elementInlineScript, url: "https://api.example.org/collect"},
+				{element: elementInlineScript, url: "//beacon.example.org/b"},
+			},
+		},
+		{
+			name: "assigned url is not reported twice",
+			html: `<script>var u = "https://a.example.com/a.js"; s.src = "https://a.example.com/a.js";</script>`,
+			want: []tagSource{{element: "script", url: "https://a.example.com/a.js"}},
+		},
+		{
+			name: "urls outside of scripts are not loaded",
+			html: `<a href="https://docs.example.com/">docs</a><p>"https://text.example.com/"</p>`,
+			want: nil,
+		},
+		{
+			name: "empty src",
+			html: `<script src=""></script><script>s.src = "";</script>`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlSources(tt.html); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("htmlSources() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanReportCheck(t *testing.T) {
+	r := &ScanReport{AllowedDomains: []string{"example.com"}}
+
+	tests := []struct {
+		url        string
+		wantHost   string
+		wantReason string
+	}{
+		{"https://example.com/a.js", "example.com", ""},
+		{"https://cdn.Example.com/a.js", "cdn.example.com", ""},
+		{"//cdn.example.com/a.js", "cdn.example.com", ""},
+		{"https://notexample.com/a.js", "notexample.com", FindingUnknownDomain},
+		{"https://example.com.evil.net/a.js", "example.com.evil.net", FindingUnknownDomain},
+		{"/local/a.js", "", ""},
+		{"https://{{CDN Host}}/a.js", "", FindingDynamicURL},
+		{"https://", "", FindingDynamicURL},
+	}
+
+	for _, tt := range tests {
+		host, reason := r.check(tt.url)
+		if host != tt.wantHost || reason != tt.wantReason {
+			t.Errorf("check(%q) = %q, %q, want %q, %q", tt.url, host, reason, tt.wantHost, tt.wantReason)
+		}
+	}
+}
+
+func TestNewScanReport(t *testing.T) {
+	cv := &tagmanager.ContainerVersion{
+		AccountId:   "1",
+		ContainerId: "2",
+		Container:   &tagmanager.Container{Name: "Site"},
+		Tag: []*tagmanager.Tag{
+			{
+				TagId: "10",
+				Name:  "Loader",
+				Type:  tagTypeCustomHTML,
+				Parameter: []*tagmanager.Parameter{{Key: "html", Value: `<script>
+var s = document.createElement('script');
+s.src = "https://cdn.example.com/ok.js";
+var t = document.createElement('script');
+t.src = "https://tracker.example.net/t.js";
+var u = document.createElement('script');
+u.src = host + "/u.js";
+</script>`}},
+			},
+			{
+				TagId:     "11",
+				Name:      "Pixel",
+				Type:      tagTypeCustomImage,
+				Parameter: []*tagmanager.Parameter{{Key: "url", Value: "https://pixel.example.org/p.gif"}},
+			},
+			{
+				TagId: "12",
+				Name:  "GA4",
+				Type:  "gaawc",
+			},
+		},
+	}
+
+	r := NewScanReport([]*tagmanager.ContainerVersion{cv}, []string{" .Example.com "})
+
+	if r.ScannedTags != 2 {
+		t.Errorf("ScannedTags = %d, want 2", r.ScannedTags)
+	}
+
+	var got [][3]string
+	for _, f := range r.Findings {
+		got = append(got, [3]string{f.TagID, f.URL, f.Reason})
+	}
+
+	want := [][3]string{
+		{"10", "https://tracker.example.net/t.js", FindingUnknownDomain},
+		{"10", `host + "/u.js"`, FindingDynamicURL},
+		{"11", "https://pixel.example.org/p.gif", FindingUnknownDomain},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings = %v, want %v", got, want)
+	}
+}
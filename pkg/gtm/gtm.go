@@ -0,0 +1,102 @@
+package gtm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+	"google.golang.org/api/tagmanager/v2"
+)
+
+// NewService returns a Tag Manager client authenticated with the credentials.
+// Errors of this package are returned without a prefix, callers add the prefix of the connector or the fleet commands.
+func NewService(ctx context.Context, ac uhttp.AuthCredentials) (*tagmanager.Service, error) {
+	httpClient, err := ac.GetClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error creating http client: %w", err)
+	}
+
+	tagmanagerService, err := tagmanager.NewService(ctx, option.WithHTTPClient(httpClient))
+	if err != nil {
+		return nil, fmt.Errorf("error creating tagmanager service: %w", err)
+	}
+
+	return tagmanagerService, nil
+}
+
+// WalkContainers calls f for every container of the accounts, or of every account the client can access when no accounts are given.
+// The walk stops early when f returns false.
+func WalkContainers(ctx context.Context, client *tagmanager.Service, accounts []string, f func(*tagmanager.Container) bool) error {
+	accountMap := make(map[string]struct{}, len(accounts))
+	for _, acc := range accounts {
+		accountMap[acc] = struct{}{}
+	}
+
+	accPageToken := ""
+	for {
+		alreq := client.Accounts.List().Context(ctx)
+
+		if accPageToken != "" {
+			alreq = alreq.PageToken(accPageToken)
+		}
+
+		al, err := alreq.Do()
+		if err != nil {
+			return fmt.Errorf("failed to list accounts: %w", err)
+		}
+
+		for _, acc := range al.Account {
+			if _, ok := accountMap[acc.AccountId]; !ok && len(accountMap) > 0 {
+				continue
+			}
+
+			pageToken := ""
+			for {
+				clreq := client.Accounts.Containers.List(acc.Path).Context(ctx)
+
+				if pageToken != "" {
+					clreq = clreq.PageToken(pageToken)
+				}
+
+				cl, err := clreq.Do()
+				if err != nil {
+					return fmt.Errorf("failed to list containers: %w", err)
+				}
+
+				for _, container := range cl.Container {
+					if !f(container) {
+						return nil
+					}
+				}
+
+				if cl.NextPageToken == "" {
+					break
+				}
+				pageToken = cl.NextPageToken
+			}
+		}
+
+		if al.NextPageToken == "" {
+			break
+		}
+		accPageToken = al.NextPageToken
+	}
+
+	return nil
+}
+
+// IsNotFound reports whether the error is a Tag Manager API not found response.
+func IsNotFound(err error) bool {
+	var gErr *googleapi.Error
+	return errors.As(err, &gErr) && gErr.Code == http.StatusNotFound
+}
+
+// IsPermissionDenied reports whether the error is a Tag Manager API forbidden response.
+func IsPermissionDenied(err error) bool {
+	var gErr *googleapi.Error
+	return errors.As(err, &gErr) && gErr.Code == http.StatusForbidden
+}